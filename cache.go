@@ -9,58 +9,221 @@ import (
 
 const defaultCacheCapacity = 124
 
+// Metric key in `cacheMetrics` of entries evicted to stay within the limits.
+const metricEvictions = "evictions"
+
+// How long past its TTL an entry is kept to be served if refreshing it fails.
+const defaultMaxStale = 24 * time.Hour
+
+// The state of a cache entry, by its age. An entry is fresh for its TTL, then
+// stale for another TTL, during which it is served while being refreshed in
+// the background (stale-while-revalidate in RFC 5861). After that it is
+// expired and only served if refreshing fails (stale-if-error), until it is
+// `maxStale` past its TTL.
+type cacheState int
+
+const (
+	cacheMiss cacheState = iota
+	cacheFresh
+	cacheStale
+	cacheExpired
+)
+
 type cachedPayload struct {
 	status int
 	header http.Header
 	body   []byte
 }
 
+// Get the approximate number of bytes used by the payload: the size of its
+// body and headers.
+func (p *cachedPayload) size() int {
+	n := len(p.body)
+	for k, values := range p.header {
+		for _, v := range values {
+			n += len(k) + len(v)
+		}
+	}
+	return n
+}
+
+// Limits on the size of an LRUCache. A limit of 0 is no limit, but at least one
+// of MaxEntries and MaxBytes must be set.
+type CacheLimits struct {
+	// Maximum number of entries
+	MaxEntries int
+
+	// Maximum total size of entries in bytes, counting their keys and the size
+	// of their payloads
+	MaxBytes int
+
+	// Maximum size of a single entry in bytes; larger entries aren't cached,
+	// so that one large payload can't evict many small ones
+	MaxEntryBytes int
+}
+
 type cacheEntry struct {
-	key       string
-	payload   *cachedPayload
-	expiresAt time.Time
+	key             string
+	payload         *cachedPayload
+	size            int
+	storedAt        time.Time
+	expiresAt       time.Time
+	revalidateUntil time.Time
+	staleUntil      time.Time
+
+	// Whether the last refresh of the entry failed, and when to retry it
+	failed  bool
+	retryAt time.Time
+}
+
+// A cached payload and its state; see `LRUCache.Lookup`.
+type cacheLookup struct {
+	payload *cachedPayload
+	state   cacheState
+	age     time.Duration
+	failed  bool
+
+	// Whether a stale payload should be refreshed
+	revalidate bool
 }
 
 type LRUCache struct {
-	capacity int
+	limits   CacheLimits
+	maxStale time.Duration
 	mu       sync.Mutex
 	items    map[string]*list.Element
 	order    *list.List
+
+	// Total size of entries in bytes
+	size int
+
+	// Second tier for entries missing from memory, if any; see `UseDisk`
+	disk *DiskCache
 }
 
 func NewLRUCache(capacity int) *LRUCache {
-	if capacity <= 0 {
+	return NewLRUCacheWithLimits(CacheLimits{MaxEntries: capacity})
+}
+
+// Create a cache bounded by `limits`, or return nil (caching nothing) if
+// neither the number nor the size of entries is limited.
+func NewLRUCacheWithLimits(limits CacheLimits) *LRUCache {
+	if limits.MaxEntries <= 0 && limits.MaxBytes <= 0 {
 		return nil
 	}
+	if limits.MaxBytes > 0 && (limits.MaxEntryBytes <= 0 || limits.MaxEntryBytes > limits.MaxBytes) {
+		limits.MaxEntryBytes = limits.MaxBytes
+	}
 	return &LRUCache{
-		capacity: capacity,
-		items:    make(map[string]*list.Element, capacity),
+		limits:   limits,
+		maxStale: defaultMaxStale,
+		items:    make(map[string]*list.Element, max(limits.MaxEntries, 0)),
 		order:    list.New(),
 	}
 }
 
-func (c *LRUCache) Get(key string) (*cachedPayload, bool) {
+// Get the number of entries in the cache and their total size in bytes.
+func (c *LRUCache) Usage() (entries, bytes int) {
 	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), c.size
+}
+
+// Store entries in `disk` as well as in memory, and look up entries missing
+// from memory there, so entries survive restarts and evictions from memory.
+func (c *LRUCache) UseDisk(disk *DiskCache) {
+	if c != nil {
+		c.disk = disk
+	}
+}
+
+// Get the payload for `key` if it is fresh.
+func (c *LRUCache) Get(key string) (*cachedPayload, bool) {
+	lookup := c.Lookup(key)
+	if lookup.state != cacheFresh {
 		return nil, false
 	}
+	return lookup.payload, true
+}
+
+// Get the payload for `key` if it is fresh, stale, or expired, along with its
+// state and age. Entries missing from memory are looked up on disk, if there
+// is a disk tier, and kept in memory again.
+func (c *LRUCache) Lookup(key string) cacheLookup {
+	if c == nil {
+		return cacheLookup{}
+	}
+	if lookup, ok := c.lookupMemory(key); ok || c.disk == nil {
+		return lookup
+	}
+
+	payload, storedAt, ttl, ok := c.disk.Get(key, c.maxStale)
+	if !ok {
+		return cacheLookup{}
+	}
+	cacheMetrics.Add(metricDiskHits, 1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		// Set while reading from disk, so newer than the payload on disk
+		entry := elem.Value.(*cacheEntry)
+		if lookup, ok := entry.lookup(time.Now()); ok {
+			return lookup
+		}
+	}
+	entry := c.store(key, payload, ttl, storedAt)
+	if entry == nil {
+		// Too large to keep in memory
+		entry = &cacheEntry{key: key}
+		c.setEntry(entry, payload, ttl, storedAt)
+	}
+	lookup, _ := entry.lookup(time.Now())
+	return lookup
+}
+
+func (c *LRUCache) lookupMemory(key string) (cacheLookup, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	elem, ok := c.items[key]
 	if !ok {
-		return nil, false
+		return cacheLookup{}, false
 	}
-	entry := elem.Value.(*cacheEntry)
-	if time.Now().After(entry.expiresAt) {
+	lookup, ok := elem.Value.(*cacheEntry).lookup(time.Now())
+	if !ok {
 		c.removeElement(elem)
-		return nil, false
+		return cacheLookup{}, false
 	}
 	c.order.MoveToFront(elem)
-	return entry.payload, true
+	return lookup, true
 }
 
-func (c *LRUCache) Set(key string, payload *cachedPayload, ttl time.Duration) {
-	if c == nil || ttl <= 0 || payload == nil {
+// Get the payload of the entry and its state at `now`, or false if it is past
+// being served at all.
+func (entry *cacheEntry) lookup(now time.Time) (cacheLookup, bool) {
+	lookup := cacheLookup{payload: entry.payload, age: now.Sub(entry.storedAt), failed: entry.failed}
+	switch {
+	case now.Before(entry.expiresAt):
+		lookup.state = cacheFresh
+	case now.Before(entry.revalidateUntil):
+		lookup.state = cacheStale
+		lookup.revalidate = !now.Before(entry.retryAt)
+	case now.Before(entry.staleUntil):
+		lookup.state = cacheExpired
+	default:
+		return cacheLookup{}, false
+	}
+	return lookup, true
+}
+
+// Record that refreshing the entry for `key` failed. It is served as stale
+// without refreshing it for `d`, so a failing data source isn't retried on
+// every request, then refreshed in the background for another `d`.
+func (c *LRUCache) Postpone(key string, d time.Duration) {
+	if c == nil {
 		return
 	}
 	c.mu.Lock()
@@ -68,33 +231,83 @@ func (c *LRUCache) Set(key string, payload *cachedPayload, ttl time.Duration) {
 
 	if elem, ok := c.items[key]; ok {
 		entry := elem.Value.(*cacheEntry)
-		entry.payload = payload
-		entry.expiresAt = time.Now().Add(ttl)
-		c.order.MoveToFront(elem)
+		entry.failed = true
+		entry.retryAt = time.Now().Add(d)
+		entry.revalidateUntil = entry.retryAt.Add(d)
+		if entry.revalidateUntil.After(entry.staleUntil) {
+			entry.revalidateUntil = entry.staleUntil
+		}
+	}
+}
+
+func (c *LRUCache) Set(key string, payload *cachedPayload, ttl time.Duration) {
+	if c == nil || ttl <= 0 || payload == nil {
 		return
 	}
-	entry := &cacheEntry{
-		key:       key,
-		payload:   payload,
-		expiresAt: time.Now().Add(ttl),
+	now := time.Now()
+	c.mu.Lock()
+	c.store(key, payload, ttl, now)
+	c.mu.Unlock()
+	c.disk.Set(key, payload, now, ttl)
+}
+
+// Store the payload for `key`, cached at `storedAt`, in memory. Returns the
+// entry, or nil if the payload is too large to cache.
+func (c *LRUCache) store(key string, payload *cachedPayload, ttl time.Duration, storedAt time.Time) *cacheEntry {
+	size := len(key) + payload.size()
+	if c.limits.MaxEntryBytes > 0 && size > c.limits.MaxEntryBytes {
+		// Too large to cache; drop any older payload too, since it's outdated
+		if elem, ok := c.items[key]; ok {
+			c.removeElement(elem)
+		}
+		return nil
+	}
+	var entry *cacheEntry
+	if elem, ok := c.items[key]; ok {
+		entry = elem.Value.(*cacheEntry)
+		c.size += size - entry.size
+		entry.size = size
+		c.setEntry(entry, payload, ttl, storedAt)
+		c.order.MoveToFront(elem)
+	} else {
+		entry = &cacheEntry{key: key, size: size}
+		c.setEntry(entry, payload, ttl, storedAt)
+		c.items[key] = c.order.PushFront(entry)
+		c.size += size
 	}
-	elem := c.order.PushFront(entry)
-	c.items[key] = elem
-	if c.order.Len() > c.capacity {
+	for c.isOverLimit() {
 		c.evictOldest()
 	}
+	return entry
+}
+
+func (c *LRUCache) isOverLimit() bool {
+	return (c.limits.MaxEntries > 0 && c.order.Len() > c.limits.MaxEntries) ||
+		(c.limits.MaxBytes > 0 && c.size > c.limits.MaxBytes)
+}
+
+func (c *LRUCache) setEntry(entry *cacheEntry, payload *cachedPayload, ttl time.Duration, storedAt time.Time) {
+	entry.payload = payload
+	entry.storedAt = storedAt
+	entry.expiresAt = storedAt.Add(ttl)
+	entry.revalidateUntil = entry.expiresAt.Add(ttl)
+	entry.staleUntil = entry.expiresAt.Add(max(ttl, c.maxStale))
+	entry.failed = false
+	entry.retryAt = time.Time{}
 }
 
 func (c *LRUCache) removeElement(elem *list.Element) {
 	entry := elem.Value.(*cacheEntry)
 	delete(c.items, entry.key)
 	c.order.Remove(elem)
+	c.size -= entry.size
 }
 
 func (c *LRUCache) evictOldest() {
 	elem := c.order.Back()
 	if elem != nil {
 		c.removeElement(elem)
+		cacheMetrics.Add(metricEvictions, 1)
 	}
 }
 
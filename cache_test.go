@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheEntryLookup(t *testing.T) {
+	c := NewLRUCache(defaultCacheCapacity)
+	storedAt := time.Now()
+	entry := c.store("GET:/v0/deptList", &cachedPayload{status: http.StatusOK}, time.Hour, storedAt)
+	tests := []struct {
+		age        time.Duration
+		state      cacheState
+		revalidate bool
+	}{
+		{age: 0, state: cacheFresh},
+		{age: 59 * time.Minute, state: cacheFresh},
+		{age: time.Hour, state: cacheStale, revalidate: true},
+		{age: 119 * time.Minute, state: cacheStale, revalidate: true},
+		{age: 2 * time.Hour, state: cacheExpired},
+		{age: time.Hour + defaultMaxStale - time.Second, state: cacheExpired},
+		{age: time.Hour + defaultMaxStale, state: cacheMiss},
+	}
+	for _, test := range tests {
+		lookup, ok := entry.lookup(storedAt.Add(test.age))
+		if ok != (test.state != cacheMiss) || lookup.state != test.state || lookup.revalidate != test.revalidate {
+			t.Errorf("lookup after %s = %v, %t; want state %d, revalidate %t", test.age, lookup, ok, test.state, test.revalidate)
+			continue
+		}
+		if ok && lookup.age != test.age {
+			t.Errorf("lookup after %s has age %s", test.age, lookup.age)
+		}
+	}
+}
+
+func TestCachePostpone(t *testing.T) {
+	const key = "GET:/v0/deptList"
+	c := NewLRUCache(defaultCacheCapacity)
+	now := time.Now()
+	entry := c.store(key, &cachedPayload{status: http.StatusOK}, time.Hour, now.Add(-3*time.Hour))
+	c.Postpone(key, staleRetryInterval)
+	tests := []struct {
+		after      time.Duration
+		state      cacheState
+		revalidate bool
+	}{
+		// Served as stale without refreshing it, then refreshed, then expired
+		{after: staleRetryInterval / 2, state: cacheStale},
+		{after: staleRetryInterval * 3 / 2, state: cacheStale, revalidate: true},
+		{after: staleRetryInterval * 5 / 2, state: cacheExpired},
+	}
+	for _, test := range tests {
+		lookup, ok := entry.lookup(now.Add(test.after))
+		if !ok || lookup.state != test.state || lookup.revalidate != test.revalidate || !lookup.failed {
+			t.Errorf("lookup %s after postponing = %v, %t; want state %d, revalidate %t, failed", test.after, lookup, ok, test.state, test.revalidate)
+		}
+	}
+
+	// Postponing doesn't keep an entry past being served at all
+	entry = c.store(key, &cachedPayload{status: http.StatusOK}, time.Hour, now.Add(-time.Hour-defaultMaxStale+time.Second))
+	c.Postpone(key, staleRetryInterval)
+	if lookup, ok := entry.lookup(now.Add(2 * time.Second)); ok {
+		t.Errorf("lookup after staleUntil = %v, want none", lookup)
+	}
+
+	// Storing a fresh payload clears the failure
+	entry = c.store(key, &cachedPayload{status: http.StatusOK}, time.Hour, now)
+	if lookup, _ := entry.lookup(now); lookup.state != cacheFresh || lookup.failed {
+		t.Errorf("lookup after storing = %v, want fresh and not failed", lookup)
+	}
+}
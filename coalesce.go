@@ -0,0 +1,93 @@
+package main
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cache metrics, published at /debug/vars on METRICS_ADDR; see serveMetrics.
+var cacheMetrics = expvar.NewMap("cache")
+
+// Metric keys in `cacheMetrics`.
+const (
+	// Cache misses that fetched from the data source
+	metricFetches = "fetches"
+
+	// Requests that missed the cache while another request with the same key
+	// was fetching, and were sent its payload instead of fetching again
+	metricDeduplicated = "deduplicated"
+)
+
+// Key in the gin context of the flight a request is leading; see
+// `serveFromCache`.
+const flightKey = "flight"
+
+// Coalesces concurrent cache misses for the same cache key, so only one
+// request per key fetches from the data source while the others wait for its
+// payload. This keeps popular keys from stampeding the data source when they
+// expire. Flights also coalesce builds of derived data, which finish without a
+// payload; see derivedCache.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// A fetch for a cache key, led by the first request to miss the cache.
+type flight struct {
+	group *flightGroup
+	key   string
+	once  sync.Once
+	done  chan struct{}
+
+	// The payload the leader sent, or nil if it didn't send one; only set
+	// once done is closed
+	payload *cachedPayload
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: make(map[string]*flight)}
+}
+
+// Join the flight for `key`, starting it if there isn't one. Returns the
+// flight and whether the caller leads it; leaders must `finish` the flight,
+// and others should wait for it to be done.
+func (g *flightGroup) join(key string) (*flight, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.flights[key]; ok {
+		return f, false
+	}
+	f := &flight{group: g, key: key, done: make(chan struct{})}
+	g.flights[key] = f
+	return f, true
+}
+
+// Send `payload` to the waiters of a flight and end it, so the next miss for
+// its key starts a new flight. Only the first call has any effect.
+func (f *flight) finish(payload *cachedPayload) {
+	f.once.Do(func() {
+		f.group.mu.Lock()
+		delete(f.group.flights, f.key)
+		f.group.mu.Unlock()
+		f.payload = payload
+		close(f.done)
+	})
+}
+
+// Finish the flight led by the request in `ctx`, if there is one, with the
+// payload being sent to the caller.
+func finishFlight(ctx *gin.Context, payload *cachedPayload) {
+	if f, ok := ctx.Get(flightKey); ok {
+		f.(*flight).finish(payload)
+	}
+}
+
+// Middleware that finishes the flight led by a request if the request ends
+// without sending a payload, such as after a panic, so its waiters don't wait
+// forever; they fetch for themselves instead.
+func finishFlights(ctx *gin.Context) {
+	defer finishFlight(ctx, nil)
+	ctx.Next()
+}
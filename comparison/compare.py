@@ -1,18 +1,22 @@
 import requests
 from bs4 import BeautifulSoup
 
-# Compare course catalog
+# Compare course catalog for the default term
+terms = requests.get("http://api.jupiterp.com/v0/terms").json()
+term = next(t['term'] for t in terms if t['is_default'])
+print(f"Comparing course catalogs for term {term}")
+
 jupiterp_courses = []
-offset = 0
+url = f"http://api.jupiterp.com/v0/courses/minified?term={term}&limit=500&count=exact"
 
-while True:
-    print(f"sending request to Jupiterp API; offset={offset}")
-    url = f"http://api.jupiterp.com/v0/courses/minified?limit=500&offset={offset}"
-    batch = requests.get(url).json()
-    if not batch:
-        break
-    jupiterp_courses.extend(batch)
-    offset += 500
+# Follow the Link header to each next page until there are no more
+while url:
+    print(f"sending request to Jupiterp API; url={url}")
+    res = requests.get(url)
+    jupiterp_courses.extend(res.json())
+    print(f"got courses {res.headers.get('Content-Range')}")
+    next_link = res.links.get("next")
+    url = requests.compat.urljoin(url, next_link["url"]) if next_link else None
 
 jupiterp_codes = [course['course_code'] for course in jupiterp_courses]
 
@@ -20,7 +24,7 @@ print(f"Found {len(jupiterp_codes)} Jupiterp course codes.")
 
 umdio_courses = []
 
-url = f"http://api.umd.io/v1/courses/list?semester=202508"
+url = f"http://api.umd.io/v1/courses/list?semester={term}"
 batch = requests.get(url).json()
 umdio_courses.extend(batch)
 
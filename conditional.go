@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Set the validators of a successful payload, so callers can make conditional
+// requests for it: a strong ETag, which is always a hash of the body, and
+// Last-Modified, the time `modified` the data was retrieved.
+func setValidators(payload *cachedPayload, modified time.Time) {
+	sum := sha256.Sum256(payload.body)
+	payload.header.Set("ETag", fmt.Sprintf(`"%x"`, sum[:16]))
+	payload.header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+}
+
+// Set the Cache-Control header of a payload cached for `ttl`, so browsers and
+// CDNs cache it for as long as the API does. As with the API's cache, it can
+// then be served stale while it's refreshed, or if refreshing fails.
+func setCacheControl(payload *cachedPayload, ttl time.Duration) {
+	seconds := int(ttl.Seconds())
+	payload.header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d, stale-if-error=%d",
+		seconds, seconds, int(max(ttl, defaultMaxStale).Seconds())))
+}
+
+// Set the headers of an error payload so browsers and CDNs don't cache it, since
+// an error shouldn't outlive the problem that caused it. Any validators, such as
+// ones passed through from the database, are removed, since only successful
+// payloads can be revalidated.
+func setNoStore(payload *cachedPayload) {
+	payload.header.Set("Cache-Control", "no-store")
+	payload.header.Del("ETag")
+	payload.header.Del("Last-Modified")
+}
+
+// Copy `payload` with an Age header, the seconds since it was cached.
+func withAge(payload *cachedPayload, age time.Duration) *cachedPayload {
+	header := payload.header.Clone()
+	header.Set("Age", strconv.Itoa(int(age.Seconds())))
+	return &cachedPayload{status: payload.status, header: header, body: payload.body}
+}
+
+// Check whether the conditional request `r` is for a representation with the
+// validators in `header`, so it can be answered with 304 Not Modified.
+// If-None-Match takes precedence over If-Modified-Since (RFC 7232).
+func isNotModified(r *http.Request, header http.Header) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		etag := header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakETag(candidate) == weakETag(etag) {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(header.Get("Last-Modified"))
+	return err == nil && !modified.After(since)
+}
+
+// Get the opaque part of an ETag, for weak comparison (RFC 7232).
+func weakETag(etag string) string {
+	return strings.TrimPrefix(etag, "W/")
+}
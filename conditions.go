@@ -0,0 +1,359 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Possible types of a Requirement.
+const (
+	RequirementAnd     = "and"
+	RequirementOr      = "or"
+	RequirementCourse  = "course"
+	RequirementProgram = "program"
+	RequirementOther   = "other" // requirement that isn't a course or program, like an exam score
+)
+
+// The structured form of a course's conditions. Each condition string is
+// sorted into one of these fields by its label (for example, "Prerequisite:"
+// or "Credit only granted for:"); strings without a known label are kept in
+// Other.
+type Conditions struct {
+	// Requirements that must be met before taking the course, or null if there
+	// are none.
+	Prerequisites *Requirement `json:"prerequisites"`
+
+	// Requirements that must be met before or while taking the course, or null
+	// if there are none.
+	Corequisites *Requirement `json:"corequisites"`
+
+	// Restrictions on who may take the course, such as department permission.
+	Restrictions []string `json:"restrictions"`
+
+	// Courses that are mutually exclusive with this course for credit. This
+	// course is usually included.
+	CreditOnlyGrantedFor []string `json:"credit_only_granted_for"`
+
+	// Course codes this course was previously offered under.
+	Formerly []string `json:"formerly"`
+
+	// Courses this course is cross-listed with.
+	CrossListedWith []string `json:"cross_listed_with"`
+
+	// Condition strings that could not be categorized.
+	Other []string `json:"other"`
+}
+
+// A node in a boolean expression tree of requirements. And and Or nodes have
+// Children; Course nodes have a CourseCode and optionally a MinGrade; Program
+// nodes have Programs, any of which satisfy the requirement; Other nodes have
+// the original Text of a requirement that could not be parsed further.
+type Requirement struct {
+	Type       string        `json:"type"`
+	Children   []Requirement `json:"children,omitempty"`
+	CourseCode string        `json:"course_code,omitempty"`
+	MinGrade   string        `json:"min_grade,omitempty"`
+	Programs   []string      `json:"programs,omitempty"`
+	Text       string        `json:"text,omitempty"`
+
+	// The number of Children of an Or node that must be met, if more than one,
+	// as in "2 courses from (MATH240, MATH241, MATH246)"
+	Count int `json:"count,omitempty"`
+
+	// The prerequisites of a Course node's course; only set when traversing
+	// the prerequisite graph.
+	Prerequisites *Requirement `json:"prerequisites,omitempty"`
+}
+
+var (
+	courseCodePattern = regexp.MustCompile(`^[A-Z]{4}[0-9]{3}[A-Z]?$`)
+	courseCodeSearch  = regexp.MustCompile(`\b[A-Z]{4}[0-9]{3}[A-Z]?\b`)
+	minGradePattern   = regexp.MustCompile(`(?i)^(?:a )?minimum grade of ([A-F][+-]?) in (.+)$`)
+	choicePattern     = regexp.MustCompile(`(?i)^(\d+|one|two|three) courses? (?:with (?:a )?minimum grade of ([A-F][+-]?) )?from (.+)$`)
+	programPattern    = regexp.MustCompile(`(?i)^must be (?:in|admitted to) (?:the |a |one of the following )?(.+?) (?:programs?|majors?)$`)
+	clauseConnector   = regexp.MustCompile(`(?i)^(and|or),? `)
+	expressionTokens  = regexp.MustCompile(`\(|\)|,|[^\s(),]+`)
+)
+
+// Parse a course's condition strings. Returns nil if the course has no
+// conditions.
+func parseConditions(raw []string) *Conditions {
+	if len(raw) == 0 {
+		return nil
+	}
+	conditions := Conditions{
+		Restrictions:         []string{},
+		CreditOnlyGrantedFor: []string{},
+		Formerly:             []string{},
+		CrossListedWith:      []string{},
+		Other:                []string{},
+	}
+	for _, r := range raw {
+		label, text, ok := strings.Cut(strings.TrimSpace(r), ":")
+		text = strings.TrimSuffix(strings.TrimSpace(text), ".")
+		if !ok {
+			conditions.Other = append(conditions.Other, strings.TrimSpace(r))
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(label)) {
+		case "prerequisite", "prerequisites":
+			conditions.Prerequisites = andRequirements(conditions.Prerequisites, parseRequirement(text))
+		case "corequisite", "corequisites":
+			conditions.Corequisites = andRequirements(conditions.Corequisites, parseRequirement(text))
+		case "restriction", "restrictions":
+			conditions.Restrictions = append(conditions.Restrictions, text)
+		case "credit only granted for":
+			conditions.CreditOnlyGrantedFor = append(conditions.CreditOnlyGrantedFor, courseCodeSearch.FindAllString(text, -1)...)
+		case "formerly":
+			conditions.Formerly = append(conditions.Formerly, courseCodeSearch.FindAllString(text, -1)...)
+		case "cross-listed with", "also offered as":
+			conditions.CrossListedWith = append(conditions.CrossListedWith, courseCodeSearch.FindAllString(text, -1)...)
+		default:
+			conditions.Other = append(conditions.Other, strings.TrimSpace(r))
+		}
+	}
+	return &conditions
+}
+
+// Combine two requirements that must both be met. Either may be nil.
+func andRequirements(a *Requirement, b Requirement) *Requirement {
+	if a == nil {
+		return &b
+	}
+	if a.Type == RequirementAnd {
+		a.Children = append(a.Children, b)
+		return a
+	}
+	return &Requirement{Type: RequirementAnd, Children: []Requirement{*a, b}}
+}
+
+// Parse the text of a prerequisite or corequisite. The text is made up of
+// clauses separated by semicolons, where each clause after the first starts
+// with "and" or "or"; for example, "Minimum grade of C- in CMSC131; or must
+// have earned a score of 5 on the A Java AP exam". Clauses are combined left
+// to right.
+func parseRequirement(text string) Requirement {
+	var result *Requirement
+	for i, clause := range strings.Split(text, ";") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		connector := RequirementAnd
+		if m := clauseConnector.FindStringSubmatch(clause); m != nil && i > 0 {
+			connector = strings.ToLower(m[1])
+			clause = strings.TrimSpace(clause[len(m[0]):])
+		}
+		next := parseClause(clause)
+		switch {
+		case result == nil:
+			result = &next
+		case result.Type == connector:
+			result.Children = append(result.Children, next)
+		default:
+			result = &Requirement{Type: connector, Children: []Requirement{*result, next}}
+		}
+	}
+	if result == nil {
+		return Requirement{Type: RequirementOther, Text: text}
+	}
+	return *result
+}
+
+// Parse a single clause of a requirement, such as "Minimum grade of C- in
+// CMSC216 and CMSC250", "1 course with a minimum grade of C- from (CMSC131,
+// CMSC133)", or "must be in the Computer Science program".
+func parseClause(clause string) Requirement {
+	other := Requirement{Type: RequirementOther, Text: clause}
+	if m := programPattern.FindStringSubmatch(clause); m != nil {
+		return Requirement{Type: RequirementProgram, Programs: splitPrograms(m[1])}
+	}
+	grade := ""
+	expression := clause
+	if m := minGradePattern.FindStringSubmatch(clause); m != nil {
+		grade = strings.ToUpper(m[1])
+		expression = m[2]
+	}
+	if m := choicePattern.FindStringSubmatch(expression); m != nil {
+		if m[2] != "" {
+			grade = strings.ToUpper(m[2])
+		}
+		requirement, ok := parseCourseChoice(m[3], choiceCounts[strings.ToLower(m[1])], grade)
+		if !ok {
+			return other
+		}
+		return requirement
+	}
+	requirement, ok := parseCourseExpression(expression, grade)
+	if !ok {
+		return other
+	}
+	return requirement
+}
+
+var choiceCounts = map[string]int{"1": 1, "one": 1, "2": 2, "two": 2, "3": 3, "three": 3}
+
+// Parse a list of courses to choose `count` of, such as "(CMSC131, CMSC133)"
+// or "MATH240 or MATH461", into an Or node. Every course is given the minimum
+// grade `grade`. Returns false if the list contains anything other than course
+// codes, or if there are fewer than `count` courses.
+func parseCourseChoice(list string, count int, grade string) (Requirement, bool) {
+	list = strings.TrimSpace(list)
+	if strings.HasPrefix(list, "(") && strings.HasSuffix(list, ")") {
+		list = list[1 : len(list)-1]
+	}
+	courses := []Requirement{}
+	for _, token := range expressionTokens.FindAllString(list, -1) {
+		switch {
+		case token == "," || strings.EqualFold(token, RequirementOr):
+		case courseCodePattern.MatchString(token):
+			courses = append(courses, Requirement{Type: RequirementCourse, CourseCode: token, MinGrade: grade})
+		default:
+			return Requirement{}, false
+		}
+	}
+	if count == 0 || count > len(courses) {
+		return Requirement{}, false
+	}
+	if count == len(courses) {
+		return joinRequirements(RequirementAnd, courses), true
+	}
+	requirement := joinRequirements(RequirementOr, courses)
+	if count > 1 {
+		requirement.Count = count
+	}
+	return requirement, true
+}
+
+// Split a list of programs such as "(Computer Science (Doctoral), Computer
+// Science (Master's))" on the commas that aren't nested in parentheses.
+func splitPrograms(s string) []string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+	}
+	programs := []string{}
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				programs = append(programs, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	last := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s[start:]), "or "))
+	return append(programs, last)
+}
+
+// Parse a boolean expression of course codes, such as "CMSC131, CMSC133, or
+// CMSC141" or "(MATH140 or MATH130) and MATH141". Every course is given the
+// minimum grade `grade`. Returns false if the expression contains anything
+// other than course codes, connectives, and parentheses.
+func parseCourseExpression(expression, grade string) (Requirement, bool) {
+	p := courseExpressionParser{tokens: expressionTokens.FindAllString(expression, -1), grade: grade}
+	requirement, ok := p.parseGroup()
+	if !ok || p.pos != len(p.tokens) {
+		return Requirement{}, false
+	}
+	return requirement, true
+}
+
+type courseExpressionParser struct {
+	tokens []string
+	pos    int
+	grade  string
+}
+
+// Parse operands separated by connectives until the end of input or a closing
+// parenthesis. A comma takes the meaning of the next "and" or "or" in the same
+// group, so "A, B, or C" is a single disjunction; "and" binds tighter than "or".
+func (p *courseExpressionParser) parseGroup() (Requirement, bool) {
+	operands := []Requirement{}
+	connectors := []string{}
+	for {
+		operand, ok := p.parseOperand()
+		if !ok {
+			return Requirement{}, false
+		}
+		operands = append(operands, operand)
+
+		// Read connectives until the next operand, keeping the strongest
+		hasConnector := false
+		connector := ","
+		for p.pos < len(p.tokens) {
+			token := strings.ToLower(p.tokens[p.pos])
+			if token != "," && token != RequirementAnd && token != RequirementOr {
+				break
+			}
+			if token != "," {
+				connector = token
+			}
+			hasConnector = true
+			p.pos++
+		}
+		if !hasConnector {
+			break
+		}
+		connectors = append(connectors, connector)
+	}
+	if len(operands) == 1 {
+		return operands[0], true
+	}
+
+	for i := len(connectors) - 1; i >= 0; i-- {
+		if connectors[i] == "," {
+			if i+1 < len(connectors) {
+				connectors[i] = connectors[i+1]
+			} else {
+				connectors[i] = RequirementAnd
+			}
+		}
+	}
+
+	// Group operands joined by "and", then join the groups with "or"
+	groups := []Requirement{}
+	current := []Requirement{operands[0]}
+	for i, connector := range connectors {
+		if connector == RequirementOr {
+			groups = append(groups, joinRequirements(RequirementAnd, current))
+			current = nil
+		}
+		current = append(current, operands[i+1])
+	}
+	groups = append(groups, joinRequirements(RequirementAnd, current))
+	return joinRequirements(RequirementOr, groups), true
+}
+
+func (p *courseExpressionParser) parseOperand() (Requirement, bool) {
+	if p.pos >= len(p.tokens) {
+		return Requirement{}, false
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	if token == "(" {
+		group, ok := p.parseGroup()
+		if !ok || p.pos >= len(p.tokens) || p.tokens[p.pos] != ")" {
+			return Requirement{}, false
+		}
+		p.pos++
+		return group, true
+	}
+	if !courseCodePattern.MatchString(token) {
+		return Requirement{}, false
+	}
+	return Requirement{Type: RequirementCourse, CourseCode: token, MinGrade: p.grade}, true
+}
+
+// Join requirements under a node of type `kind`, unless there is only one.
+func joinRequirements(kind string, requirements []Requirement) Requirement {
+	if len(requirements) == 1 {
+		return requirements[0]
+	}
+	return Requirement{Type: kind, Children: requirements}
+}
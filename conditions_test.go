@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func course(code, grade string) Requirement {
+	return Requirement{Type: RequirementCourse, CourseCode: code, MinGrade: grade}
+}
+
+func TestParseConditions(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           []string
+		prerequisites *Requirement
+		corequisites  *Requirement
+		other         []string
+	}{
+		{
+			name: "single course corequisite",
+			raw:  []string{"Corequisite: MATH140. "},
+			corequisites: &Requirement{
+				Type: RequirementCourse, CourseCode: "MATH140",
+			},
+		},
+		{
+			name:          "minimum grade in courses",
+			raw:           []string{"Prerequisite: Minimum grade of C- in CMSC132 and MATH141."},
+			prerequisites: &Requirement{Type: RequirementAnd, Children: []Requirement{course("CMSC132", "C-"), course("MATH141", "C-")}},
+		},
+		{
+			name: "one course from a list",
+			raw:  []string{"Prerequisite: 1 course with a minimum grade of C- from (CMSC131, CMSC133)."},
+			prerequisites: &Requirement{Type: RequirementOr, Children: []Requirement{
+				course("CMSC131", "C-"), course("CMSC133", "C-"),
+			}},
+		},
+		{
+			name: "course and one course from a list",
+			raw:  []string{"Prerequisite: Minimum grade of C- in CMSC330; and 1 course with a minimum grade of C- from (CMSC351, CMSC451)."},
+			prerequisites: &Requirement{Type: RequirementAnd, Children: []Requirement{
+				course("CMSC330", "C-"),
+				{Type: RequirementOr, Children: []Requirement{course("CMSC351", "C-"), course("CMSC451", "C-")}},
+			}},
+		},
+		{
+			name: "two courses from a list",
+			raw:  []string{"Prerequisite: 2 courses from (MATH240, MATH241, MATH246)."},
+			prerequisites: &Requirement{Type: RequirementOr, Count: 2, Children: []Requirement{
+				course("MATH240", ""), course("MATH241", ""), course("MATH246", ""),
+			}},
+		},
+		{
+			name: "minimum grade in one course from a list",
+			raw:  []string{"Prerequisite: Minimum grade of C- in 1 course from (MATH140, MATH220)."},
+			prerequisites: &Requirement{Type: RequirementOr, Children: []Requirement{
+				course("MATH140", "C-"), course("MATH220", "C-"),
+			}},
+		},
+		{
+			name: "course or exam",
+			raw: []string{"Prerequisite: Minimum grade of C- in CMSC131; or must have earned a score of 5 on the A Java AP exam; " +
+				"or must have earned a satisfactory score on the CMSC departmental placement exam."},
+			prerequisites: &Requirement{Type: RequirementOr, Children: []Requirement{
+				course("CMSC131", "C-"),
+				{Type: RequirementOther, Text: "must have earned a score of 5 on the A Java AP exam"},
+				{Type: RequirementOther, Text: "must have earned a satisfactory score on the CMSC departmental placement exam"},
+			}},
+		},
+		{
+			name: "grouped courses",
+			raw:  []string{"Prerequisite: Minimum grade of C- in (MATH140 or MATH130) and MATH141."},
+			prerequisites: &Requirement{Type: RequirementAnd, Children: []Requirement{
+				{Type: RequirementOr, Children: []Requirement{course("MATH140", "C-"), course("MATH130", "C-")}},
+				course("MATH141", "C-"),
+			}},
+		},
+		{
+			name:          "program",
+			raw:           []string{"Prerequisite: Must be in the Computer Science program."},
+			prerequisites: &Requirement{Type: RequirementProgram, Programs: []string{"Computer Science"}},
+		},
+		{
+			name: "course list with other text",
+			raw:  []string{"Prerequisite: 1 course from (CMSC131, or permission of instructor)."},
+			prerequisites: &Requirement{
+				Type: RequirementOther, Text: "1 course from (CMSC131, or permission of instructor)",
+			},
+		},
+		{
+			name:  "unlabeled",
+			raw:   []string{"Additional information: Some sections are online."},
+			other: []string{"Additional information: Some sections are online."},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conditions := parseConditions(test.raw)
+			assertJSON(t, "prerequisites", conditions.Prerequisites, test.prerequisites)
+			assertJSON(t, "corequisites", conditions.Corequisites, test.corequisites)
+			if test.other == nil {
+				test.other = []string{}
+			}
+			assertJSON(t, "other", conditions.Other, test.other)
+		})
+	}
+}
+
+func assertJSON(t *testing.T, name string, got, want any) {
+	t.Helper()
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("%s = %s, want %s", name, gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// A DataSource retrieves course, section, instructor, and department data
+// from some backing store. Handlers only depend on this interface, so the
+// PostgREST-backed SupabaseClient can be swapped for another backend (for
+// instance, a local database or an in-memory store) without changing them.
+type DataSource interface {
+	// Get a list of courses, without section info, that match the given args.
+	Courses(args CoursesArgs) ([]Course, error)
+
+	// Get a list of courses, with only the course code and name, that match
+	// the given args.
+	MinifiedCourses(args CoursesArgs) ([]MinifiedCourse, error)
+
+	// Get a list of courses that match the given args, each with its sections.
+	CoursesWithSections(args CoursesWithSectionsArgs) ([]CourseWithSections, error)
+
+	// Get a list of sections for one or many courses.
+	Sections(args SectionsArgs) ([]Section, error)
+
+	// Get the instructor names of each section that matches the given args,
+	// without the rest of the section.
+	SectionInstructors(args SectionsArgs) ([][]string, error)
+
+	// Get a list of instructors (including inactive ones) and their ratings.
+	Instructors(args InstructorArgs) ([]Instructor, error)
+
+	// Get a list of instructors currently teaching a course and their ratings.
+	ActiveInstructors(args InstructorArgs) ([]Instructor, error)
+
+	// Count the courses that match the given args, ignoring Offset and Limit.
+	CountCourses(args CoursesArgs) (int, error)
+
+	// Count the courses with sections that match the given args, ignoring
+	// Offset and Limit.
+	CountCoursesWithSections(args CoursesWithSectionsArgs) (int, error)
+
+	// Count the sections that match the given args, ignoring Offset and Limit.
+	CountSections(args SectionsArgs) (int, error)
+
+	// Count the instructors that match the given args, ignoring Offset and
+	// Limit.
+	CountInstructors(args InstructorArgs) (int, error)
+
+	// Count the active instructors that match the given args, ignoring Offset
+	// and Limit.
+	CountActiveInstructors(args InstructorArgs) (int, error)
+
+	// Get a list of all 4-letter department codes.
+	Departments() ([]Department, error)
+
+	// Get a list of terms (semesters) with data available, oldest first.
+	Terms() ([]Term, error)
+}
+
+/* ================================= MODELS ================================ */
+
+// A course, without any section info.
+type Course struct {
+	CourseCode  string   `json:"course_code"`
+	Name        string   `json:"name"`
+	MinCredits  int      `json:"min_credits"`
+	MaxCredits  *int     `json:"max_credits"`
+	GenEds      []string `json:"gen_eds"`
+	Conditions  []string `json:"conditions"`
+	Description *string  `json:"description"`
+	Term        string   `json:"term,omitempty"`
+
+	// Structured form of Conditions; only set when requested. See Conditions.
+	ParsedConditions *Conditions `json:"parsed_conditions,omitempty"`
+}
+
+// A course with only its code and name.
+type MinifiedCourse struct {
+	CourseCode string `json:"course_code"`
+	Name       string `json:"name"`
+}
+
+// A course along with all of its (potentially filtered) sections.
+type CourseWithSections struct {
+	Course
+	Sections []Section `json:"sections"`
+}
+
+// A single section of a course. ParsedMeetings is derived from Meetings when
+// a section is decoded; see Meeting.
+type Section struct {
+	CourseCode     string    `json:"course_code"`
+	SecCode        string    `json:"sec_code"`
+	Instructors    []string  `json:"instructors"`
+	Meetings       []string  `json:"meetings"`
+	ParsedMeetings []Meeting `json:"parsed_meetings"`
+	OpenSeats      int       `json:"open_seats"`
+	TotalSeats     int       `json:"total_seats"`
+	Waitlist       int       `json:"waitlist"`
+	Holdfile       *int      `json:"holdfile"`
+	Term           string    `json:"term,omitempty"`
+
+	// The average rating of the section's instructors, or nil if none of them
+	// are rated; only set when filtering or sorting by instructor rating.
+	InstructorRating *float64 `json:"instructor_rating,omitempty"`
+
+	// The PlanetTerp instructor for each name in Instructors, or null if the
+	// name couldn't be linked; only set when requested.
+	LinkedInstructors []*Instructor `json:"linked_instructors,omitempty"`
+}
+
+// An instructor and their average rating on PlanetTerp. Term is only set for
+// active instructors, and is the term they are teaching in.
+type Instructor struct {
+	Slug          string   `json:"slug"`
+	Name          string   `json:"name"`
+	AverageRating *float64 `json:"average_rating"`
+	Term          string   `json:"term,omitempty"`
+}
+
+// A 4-letter department code and the name of the department.
+type Department struct {
+	DeptCode string `json:"dept_code"`
+	Name     string `json:"name"`
+}
+
+// A term (semester) with data available. Terms are identified by the year and
+// starting month of the semester; for example, 202508 for Fall 2025.
+type Term struct {
+	Term      string `json:"term"`
+	Name      string `json:"name"`
+	IsDefault bool   `json:"is_default"`
+}
+
+/* ================================= ERRORS ================================ */
+
+// An UpstreamError is returned by a DataSource when its backing store rejects
+// a query (for example, PostgREST rejecting a malformed filter). Handlers pass
+// the status and body through to the caller unchanged.
+type UpstreamError struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("upstream returned status %d: %s", e.Status, e.Body)
+}
+
+// A NotFoundError is returned when a single requested resource, such as one
+// course, doesn't exist. Handlers send it to the caller as a 404.
+type NotFoundError struct {
+	Message string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Data derived from every row of a table, such as search indexes and
+// prerequisite graphs, by key (usually a term). Derived data is built for the
+// server's own use rather than sent as a response, so it's kept in process
+// here instead of in the LRUCache: it isn't bound by MaxEntryBytes, isn't
+// written to the disk tier, and doesn't evict responses. Values are rebuilt
+// after the cache's TTL.
+//
+// Building a value pages through a whole table, so concurrent builds of the
+// same key are coalesced into one, and an expired value is still served while
+// it's rebuilt in the background, or if rebuilding it fails.
+type derivedCache[V any] struct {
+	ttl     time.Duration
+	flights *flightGroup
+	mu      sync.Mutex
+	values  map[string]derivedValue[V]
+}
+
+type derivedValue[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func newDerivedCache[V any](ttl time.Duration) *derivedCache[V] {
+	return &derivedCache[V]{ttl: ttl, flights: newFlightGroup(), values: map[string]derivedValue[V]{}}
+}
+
+// Get the value for `key`, calling `build` to build it if it isn't cached or
+// has expired. If another call is already building it, waits for that build
+// instead, unless there is an expired value to return in the meantime. Errors
+// from `build` aren't cached.
+func (c *derivedCache[V]) get(key string, build func() (V, error)) (V, error) {
+	for {
+		cached, ok := c.lookup(key)
+		if ok && time.Now().Before(cached.expiresAt) {
+			return cached.value, nil
+		}
+		f, leader := c.flights.join(key)
+		if leader {
+			// Built by a flight that finished since the lookup
+			if latest, _ := c.lookup(key); time.Now().Before(latest.expiresAt) {
+				f.finish(nil)
+				return latest.value, nil
+			}
+		}
+		switch {
+		case leader && ok:
+			go func() {
+				defer f.finish(nil)
+				if _, err := c.build(key, build); err != nil {
+					log.Printf("Failed to rebuild derived data for key %q, so serving the expired data: %s", key, err)
+				}
+			}()
+			return cached.value, nil
+		case leader:
+			defer f.finish(nil)
+			return c.build(key, build)
+		case ok:
+			return cached.value, nil
+		}
+		// The build may have failed, in which case the loop tries again
+		<-f.done
+	}
+}
+
+func (c *derivedCache[V]) lookup(key string) (derivedValue[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.values[key]
+	return cached, ok
+}
+
+// Build the value for `key` and cache it if building it succeeds.
+func (c *derivedCache[V]) build(key string, build func() (V, error)) (V, error) {
+	value, err := build()
+	if err != nil {
+		return value, err
+	}
+	c.mu.Lock()
+	c.values[key] = derivedValue[V]{value: value, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return value, nil
+}
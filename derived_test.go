@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDerivedCacheCoalescesBuilds(t *testing.T) {
+	c := newDerivedCache[int](time.Hour)
+	var builds atomic.Int32
+	release := make(chan struct{})
+	build := func() (int, error) {
+		builds.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 20)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.get("202508", build)
+		}()
+	}
+	// Let the callers join the flight before the build finishes
+	for !isFlying(c.flights, "202508") {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := builds.Load(); n != 1 {
+		t.Errorf("built %d times, want 1", n)
+	}
+	for i, result := range results {
+		if result != 42 {
+			t.Errorf("result %d = %d, want 42", i, result)
+		}
+	}
+}
+
+func TestDerivedCacheServesExpiredValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		buildErr error
+		want     int
+	}{
+		{name: "rebuilt", want: 2},
+		{name: "rebuild failed", buildErr: errors.New("database is down"), want: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newDerivedCache[int](time.Hour)
+			if _, err := c.get("", func() (int, error) { return 1, nil }); err != nil {
+				t.Fatal(err)
+			}
+			c.values[""] = derivedValue[int]{value: 1, expiresAt: time.Now().Add(-time.Second)}
+
+			rebuilt := make(chan struct{})
+			got, err := c.get("", func() (int, error) {
+				defer close(rebuilt)
+				return 2, test.buildErr
+			})
+			if err != nil || got != 1 {
+				t.Fatalf("get while rebuilding = %d, %v; want the expired value 1", got, err)
+			}
+			<-rebuilt
+			// Wait for the rebuild to finish its flight
+			for isFlying(c.flights, "") {
+				time.Sleep(time.Millisecond)
+			}
+
+			got, err = c.get("", func() (int, error) { return test.want, test.buildErr })
+			if err != nil || got != test.want {
+				t.Errorf("get after rebuilding = %d, %v; want %d", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestDerivedCacheDoesNotCacheErrors(t *testing.T) {
+	c := newDerivedCache[int](time.Hour)
+	if _, err := c.get("", func() (int, error) { return 0, errors.New("database is down") }); err == nil {
+		t.Fatal("got no error, want the build's error")
+	}
+	got, err := c.get("", func() (int, error) { return 3, nil })
+	if err != nil || got != 3 {
+		t.Errorf("get after a failed build = %d, %v; want 3", got, err)
+	}
+}
+
+// Check whether there is a flight for `key` in `g`.
+func isFlying(g *flightGroup, key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.flights[key] != nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDiskCacheBytes = 256 << 20
+
+// Extension of cache files in a DiskCache. The version changes whenever
+// `diskRecord` does, so files in an older format are ignored.
+const diskCacheExt = ".v1.cache"
+
+// Metric keys in `cacheMetrics`.
+const (
+	// Lookups that missed in memory but were found on disk
+	metricDiskHits = "diskHits"
+
+	// Files evicted to stay within the disk cache's size
+	metricDiskEvictions = "diskEvictions"
+)
+
+// A DiskCache is a second cache tier beneath an LRUCache, storing payloads as
+// flat files in a directory so they survive restarts. Each payload is stored
+// with the time it was cached and its TTL, so it expires at the same time as
+// it would have in memory. The total size of the files is bounded, evicting
+// the least recently used files first.
+type DiskCache struct {
+	dir      string
+	maxBytes int
+
+	mu    sync.Mutex
+	files map[string]*diskFile // by file name
+	size  int
+}
+
+// A cache file in a DiskCache's index.
+type diskFile struct {
+	size     int
+	lastUsed time.Time
+}
+
+// The contents of a cache file.
+type diskRecord struct {
+	Key      string
+	Status   int
+	Header   http.Header
+	Body     []byte
+	StoredAt time.Time
+	TTL      time.Duration
+}
+
+// Open a disk cache in `dir`, creating the directory if needed and indexing
+// any files already in it, up to `maxBytes` in total.
+func NewDiskCache(dir string, maxBytes int) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	d := &DiskCache{dir: dir, maxBytes: maxBytes, files: make(map[string]*diskFile, len(entries))}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			// Left over from a write that was interrupted
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		if entry.IsDir() || !strings.HasSuffix(name, diskCacheExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		d.files[name] = &diskFile{size: int(info.Size()), lastUsed: info.ModTime()}
+		d.size += int(info.Size())
+	}
+	d.mu.Lock()
+	d.evict()
+	d.mu.Unlock()
+	log.Printf("Opened disk cache in %s with %d files and %d bytes", dir, len(d.files), d.size)
+	return d, nil
+}
+
+// Get the name of the file storing the payload for `key`.
+func diskFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + diskCacheExt
+}
+
+// Get the payload for `key`, along with the time it was cached and its TTL,
+// if it is on disk and hasn't passed `maxStale` after its TTL.
+func (d *DiskCache) Get(key string, maxStale time.Duration) (*cachedPayload, time.Time, time.Duration, bool) {
+	if d == nil {
+		return nil, time.Time{}, 0, false
+	}
+	name := diskFileName(key)
+	d.mu.Lock()
+	file, ok := d.files[name]
+	if ok {
+		file.lastUsed = time.Now()
+	}
+	d.mu.Unlock()
+	if !ok {
+		return nil, time.Time{}, 0, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(d.dir, name))
+	var record diskRecord
+	if err == nil {
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&record)
+	}
+	if err != nil || record.Key != key {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Removing unreadable disk cache file %s: %v", name, err)
+		}
+		d.remove(name)
+		return nil, time.Time{}, 0, false
+	}
+	if time.Now().After(record.StoredAt.Add(record.TTL + max(record.TTL, maxStale))) {
+		d.remove(name)
+		return nil, time.Time{}, 0, false
+	}
+	payload := &cachedPayload{status: record.Status, header: record.Header, body: record.Body}
+	return payload, record.StoredAt, record.TTL, true
+}
+
+// Store the payload for `key`, cached at `storedAt` for `ttl`. Payloads
+// larger than 1/8 of the cache's size aren't stored, so that one large payload
+// can't evict many small ones. Errors are logged, since the cache is only an
+// optimization.
+func (d *DiskCache) Set(key string, payload *cachedPayload, storedAt time.Time, ttl time.Duration) {
+	if d == nil {
+		return
+	}
+	name := diskFileName(key)
+	var buf bytes.Buffer
+	record := diskRecord{
+		Key:      key,
+		Status:   payload.status,
+		Header:   payload.header,
+		Body:     payload.body,
+		StoredAt: storedAt,
+		TTL:      ttl,
+	}
+	if err := gob.NewEncoder(&buf).Encode(record); err != nil {
+		log.Printf("Failed to encode disk cache file for key %s: %s", key, err)
+		return
+	}
+	if buf.Len() > d.maxBytes/8 {
+		d.remove(name)
+		return
+	}
+
+	// Write to a temporary file first, so readers never see a partial file
+	tmp, err := os.CreateTemp(d.dir, "*.tmp")
+	if err != nil {
+		log.Printf("Failed to write disk cache file for key %s: %s", key, err)
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(d.dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		log.Printf("Failed to write disk cache file for key %s: %s", key, err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if file, ok := d.files[name]; ok {
+		d.size -= file.size
+	}
+	d.files[name] = &diskFile{size: buf.Len(), lastUsed: time.Now()}
+	d.size += buf.Len()
+	d.evict()
+}
+
+// Get the number of files in the cache and their total size in bytes.
+func (d *DiskCache) Usage() (files, bytes int) {
+	if d == nil {
+		return 0, 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.files), d.size
+}
+
+func (d *DiskCache) remove(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.removeLocked(name)
+}
+
+func (d *DiskCache) removeLocked(name string) {
+	file, ok := d.files[name]
+	if !ok {
+		return
+	}
+	if err := os.Remove(filepath.Join(d.dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Failed to remove disk cache file %s: %s", name, err)
+	}
+	delete(d.files, name)
+	d.size -= file.size
+}
+
+// Remove the least recently used files until the cache is within its size.
+func (d *DiskCache) evict() {
+	for d.size > d.maxBytes && len(d.files) > 0 {
+		var oldest string
+		var oldestUsed time.Time
+		for name, file := range d.files {
+			if oldest == "" || file.lastUsed.Before(oldestUsed) {
+				oldest, oldestUsed = name, file.lastUsed
+			}
+		}
+		d.removeLocked(oldest)
+		cacheMetrics.Add(metricDiskEvictions, 1)
+	}
+}
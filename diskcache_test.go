@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Create a payload with a JSON body of `n` bytes.
+func payloadOfSize(n int) *cachedPayload {
+	header := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+	return &cachedPayload{status: http.StatusOK, header: header, body: bytes.Repeat([]byte("1"), n)}
+}
+
+// Check whether the file for `key` exists in `d`'s directory.
+func hasDiskFile(t *testing.T, d *DiskCache, key string) bool {
+	t.Helper()
+	_, err := os.Stat(filepath.Join(d.dir, diskFileName(key)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestDiskCacheRoundTrip(t *testing.T) {
+	d, err := NewDiskCache(t.TempDir(), defaultDiskCacheBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := payloadOfSize(100)
+	storedAt := time.Now().Add(-time.Minute)
+	d.Set("GET:/v0/deptList", want, storedAt, time.Hour)
+
+	got, gotStoredAt, ttl, ok := d.Get("GET:/v0/deptList", defaultMaxStale)
+	if !ok {
+		t.Fatal("got no payload, want the stored one")
+	}
+	if got.status != want.status || !bytes.Equal(got.body, want.body) ||
+		got.header.Get("Content-Type") != want.header.Get("Content-Type") {
+		t.Errorf("payload = %+v, want %+v", got, want)
+	}
+	if !gotStoredAt.Equal(storedAt) || ttl != time.Hour {
+		t.Errorf("stored at %s for %s, want %s for %s", gotStoredAt, ttl, storedAt, time.Hour)
+	}
+	if _, _, _, ok := d.Get("GET:/v0/terms", defaultMaxStale); ok {
+		t.Error("got a payload for a key that wasn't stored")
+	}
+	if files, _ := d.Usage(); files != 1 {
+		t.Errorf("usage = %d files, want 1", files)
+	}
+}
+
+func TestDiskCacheExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		age      time.Duration
+		maxStale time.Duration
+		ok       bool
+	}{
+		{name: "fresh", age: time.Minute, maxStale: time.Hour, ok: true},
+		{name: "expired", age: 2*time.Hour - time.Minute, maxStale: time.Hour, ok: true},
+		{name: "past maxStale", age: 2*time.Hour + time.Minute, maxStale: time.Hour, ok: false},
+		{name: "maxStale shorter than TTL", age: 2*time.Hour - time.Minute, maxStale: time.Minute, ok: true},
+		{name: "past TTL twice", age: 2*time.Hour + time.Minute, maxStale: time.Minute, ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := NewDiskCache(t.TempDir(), defaultDiskCacheBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.Set("key", payloadOfSize(10), time.Now().Add(-test.age), time.Hour)
+			if _, _, _, ok := d.Get("key", test.maxStale); ok != test.ok {
+				t.Errorf("found = %t, want %t", ok, test.ok)
+			}
+			if hasDiskFile(t, d, "key") != test.ok {
+				t.Errorf("file exists = %t, want %t", !test.ok, test.ok)
+			}
+		})
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	// Measure the size of a file, then fit 8 of them in the cache
+	measure, err := NewDiskCache(t.TempDir(), defaultDiskCacheBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	measure.Set("key0", payloadOfSize(100), time.Now(), time.Hour)
+	_, size := measure.Usage()
+
+	d, err := NewDiskCache(t.TempDir(), 8*size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 8 {
+		d.Set(fmt.Sprintf("key%d", i), payloadOfSize(100), time.Now(), time.Hour)
+	}
+	if _, _, _, ok := d.Get("key0", defaultMaxStale); !ok {
+		t.Fatal("key0 was evicted before the cache was full")
+	}
+	d.Set("key8", payloadOfSize(100), time.Now(), time.Hour)
+
+	if files, bytes := d.Usage(); files != 8 || bytes != 8*size {
+		t.Errorf("usage = %d files and %d bytes, want 8 and %d", files, bytes, 8*size)
+	}
+	for _, key := range []string{"key0", "key2", "key8"} {
+		if !hasDiskFile(t, d, key) {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if hasDiskFile(t, d, "key1") {
+		t.Error("key1 wasn't evicted, but was the least recently used")
+	}
+}
+
+func TestDiskCacheLargePayload(t *testing.T) {
+	d, err := NewDiskCache(t.TempDir(), 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Set("key", payloadOfSize(10), time.Now(), time.Hour)
+	if !hasDiskFile(t, d, "key") {
+		t.Fatal("small payload wasn't stored")
+	}
+
+	// A payload over 1/8 of the cache replaces the older one with nothing
+	d.Set("key", payloadOfSize(2048/8), time.Now(), time.Hour)
+	if _, _, _, ok := d.Get("key", defaultMaxStale); ok {
+		t.Error("got a payload larger than 1/8 of the cache, want none")
+	}
+	if files, bytes := d.Usage(); files != 0 || bytes != 0 {
+		t.Errorf("usage = %d files and %d bytes, want none", files, bytes)
+	}
+}
+
+func TestDiskCacheUnreadableFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents func(d *DiskCache) []byte
+	}{
+		{name: "not gob", contents: func(*DiskCache) []byte { return []byte("not a cache file") }},
+		{name: "truncated", contents: func(d *DiskCache) []byte {
+			data, err := os.ReadFile(filepath.Join(d.dir, diskFileName("key")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return data[:len(data)/2]
+		}},
+		{name: "another key", contents: func(d *DiskCache) []byte {
+			d.Set("other", payloadOfSize(10), time.Now(), time.Hour)
+			data, err := os.ReadFile(filepath.Join(d.dir, diskFileName("other")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return data
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := NewDiskCache(t.TempDir(), defaultDiskCacheBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.Set("key", payloadOfSize(10), time.Now(), time.Hour)
+			if err := os.WriteFile(filepath.Join(d.dir, diskFileName("key")), test.contents(d), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, _, ok := d.Get("key", defaultMaxStale); ok {
+				t.Error("got a payload from an unreadable file, want none")
+			}
+			if hasDiskFile(t, d, "key") {
+				t.Error("unreadable file wasn't removed")
+			}
+		})
+	}
+}
+
+func TestDiskCacheReopen(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDiskCache(dir, defaultDiskCacheBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Set("key0", payloadOfSize(10), time.Now(), time.Hour)
+	d.Set("key1", payloadOfSize(20), time.Now(), time.Hour)
+	files, size := d.Usage()
+	for _, name := range []string{"123.tmp", "notes.txt", "abc.v0.cache"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("left over"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := NewDiskCache(dir, defaultDiskCacheBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotFiles, gotSize := reopened.Usage(); gotFiles != files || gotSize != size {
+		t.Errorf("usage after reopening = %d files and %d bytes, want %d and %d", gotFiles, gotSize, files, size)
+	}
+	if payload, _, _, ok := reopened.Get("key1", defaultMaxStale); !ok || len(payload.body) != 20 {
+		t.Errorf("Get(key1) after reopening = %v, %t; want the stored payload", payload, ok)
+	}
+	for name, want := range map[string]bool{"123.tmp": false, "notes.txt": true, "abc.v0.cache": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists after reopening = %t, want %t", name, got, want)
+		}
+	}
+
+	// Reopening with a smaller size evicts files to fit
+	small, err := NewDiskCache(dir, size-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := small.Usage(); files != 1 {
+		t.Errorf("usage after reopening smaller = %d files, want 1", files)
+	}
+}
+
+func TestLRUCacheUsesDisk(t *testing.T) {
+	d, err := NewDiskCache(t.TempDir(), defaultDiskCacheBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewLRUCache(1)
+	c.UseDisk(d)
+	c.Set("key0", payloadOfSize(10), time.Hour)
+	c.Set("key1", payloadOfSize(20), time.Hour)
+	if _, ok := c.items["key0"]; ok {
+		t.Fatal("key0 wasn't evicted from memory")
+	}
+
+	lookup := c.Lookup("key0")
+	if lookup.state != cacheFresh || len(lookup.payload.body) != 10 {
+		t.Errorf("Lookup(key0) = %v, want the fresh payload from disk", lookup)
+	}
+	if _, ok := c.items["key0"]; !ok {
+		t.Error("key0 wasn't kept in memory again after reading it from disk")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Key in the gin context of the term a request was resolved to; see
+// resolveTerm.
+const resolvedTermKey = "term"
+
+// The envelope around every successful v1 response. v0 responses are the bare
+// data, so they're kept as-is for existing callers.
+type Envelope struct {
+	Data  any           `json:"data"`
+	Meta  EnvelopeMeta  `json:"meta"`
+	Links EnvelopeLinks `json:"links"`
+}
+
+// Metadata about the data in an envelope.
+type EnvelopeMeta struct {
+	// The total number of results in a list; only set when requested with
+	// count=exact.
+	Total *int `json:"total,omitempty"`
+
+	// The limit and offset of the page of a list; only set for lists.
+	Limit  *int `json:"limit,omitempty"`
+	Offset *int `json:"offset,omitempty"`
+
+	// The cursor for the next page of a list, if there may be more results.
+	NextCursor string `json:"nextCursor,omitempty"`
+
+	// The term the data is for, if it is for a single term.
+	Term string `json:"term,omitempty"`
+
+	// When the data was retrieved from the data source. Responses are cached,
+	// so this may be earlier than the request.
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+// Links related to the data in an envelope, as paths with query strings.
+type EnvelopeLinks struct {
+	Self string `json:"self"`
+
+	// The next and previous pages of a list, if there are any.
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// Check whether `ctx` is a request to the v1 API, whose responses are wrapped
+// in an Envelope.
+func isV1(ctx *gin.Context) bool {
+	return strings.HasPrefix(ctx.Request.URL.Path, "/v1/")
+}
+
+// Wrap `result` in an Envelope for v1 requests; v0 results are returned
+// unchanged. `p` describes the page `result` is of, or is nil if it isn't a
+// list.
+func wrapResult(ctx *gin.Context, result any, p *page) any {
+	if !isV1(ctx) {
+		return result
+	}
+	envelope := Envelope{
+		Data: result,
+		Meta: EnvelopeMeta{
+			Term:        ctx.GetString(resolvedTermKey),
+			LastUpdated: time.Now().UTC().Truncate(time.Second),
+		},
+		Links: EnvelopeLinks{Self: requestPath(ctx.Request)},
+	}
+	if p != nil {
+		links := p.links(ctx.Request)
+		envelope.Meta.Limit, envelope.Meta.Offset = &p.limit, &p.offset
+		envelope.Meta.NextCursor = links.NextCursor
+		envelope.Links.Next, envelope.Links.Prev = links.Next, links.Prev
+		if p.total >= 0 {
+			envelope.Meta.Total = &p.total
+		}
+	}
+	return envelope
+}
+
+// Get the path and query string of `r`.
+func requestPath(r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return r.URL.Path
+	}
+	return r.URL.Path + "?" + r.URL.RawQuery
+}
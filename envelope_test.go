@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// Pins the bodies of v0 responses for the fixtures, so changes to them are
+// deliberate. Run with -update to rewrite the golden files after one.
+func TestV0Golden(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{name: "courses", target: "/v0/courses?courseCodes=CMSC131,MATH141"},
+		{name: "courses_minified", target: "/v0/courses/minified?prefix=CMSC"},
+		{name: "courses_with_sections", target: "/v0/courses/withSections?courseCodes=CMSC131"},
+		{name: "course", target: "/v0/courses/CMSC330"},
+		{name: "sections", target: "/v0/sections?courseCodes=CMSC131,MATH140"},
+		{name: "instructors", target: "/v0/instructors?limit=3"},
+		{name: "instructors_active", target: "/v0/instructors/active?limit=3"},
+		{name: "departments", target: "/v0/deptList"},
+		{name: "terms", target: "/v0/terms"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(server, http.MethodGet, test.target, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want application/json; charset=utf-8", got)
+			}
+			if got := w.Header().Get("Content-Range"); got != "" {
+				t.Errorf("Content-Range = %q without count=exact, want none", got)
+			}
+
+			path := filepath.Join("testdata", "v0", test.name+".json")
+			if *updateGolden {
+				if err := os.WriteFile(path, w.Body.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(w.Body.Bytes(), want) {
+				t.Errorf("GET %s =\n%s\nwant\n%s", test.target, w.Body, want)
+			}
+
+			// The data of the v1 response is the same as the v0 response
+			w = serve(server, http.MethodGet, "/v1"+test.target[len("/v0"):], "")
+			var envelope struct{ Data json.RawMessage }
+			if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(envelope.Data, want) {
+				t.Errorf("data of v1 response =\n%s\nwant\n%s", envelope.Data, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A FixtureSource serves course, section, instructor, and department data
+// from local fixture files held in memory. It evaluates the same filters that
+// SupabaseClient translates into PostgREST queries, so the API can be run
+// without a Supabase project (for instance, offline or in CI).
+//
+// Each table is read from `<dir>/<table>.json` (a JSON array) or
+// `<dir>/<table>.ndjson` (one JSON object per line), where table is one of
+// courses, sections, instructors, active_instructors, departments, or terms.
+// Missing tables are treated as empty.
+type FixtureSource struct {
+	courses           []Course
+	sections          []Section
+	instructors       []Instructor
+	activeInstructors []Instructor
+	departments       []Department
+	terms             []Term
+}
+
+// Load all fixture tables from `dir`.
+func NewFixtureSource(dir string) (*FixtureSource, error) {
+	f := &FixtureSource{}
+	var err error
+	if f.courses, err = loadFixture[Course](dir, "courses"); err != nil {
+		return nil, err
+	}
+	if f.sections, err = loadFixture[Section](dir, "sections"); err != nil {
+		return nil, err
+	}
+	if f.instructors, err = loadFixture[Instructor](dir, "instructors"); err != nil {
+		return nil, err
+	}
+	if f.activeInstructors, err = loadFixture[Instructor](dir, "active_instructors"); err != nil {
+		return nil, err
+	}
+	if f.departments, err = loadFixture[Department](dir, "departments"); err != nil {
+		return nil, err
+	}
+	if f.terms, err = loadFixture[Term](dir, "terms"); err != nil {
+		return nil, err
+	}
+	sort.Slice(f.departments, func(i, j int) bool {
+		return f.departments[i].DeptCode < f.departments[j].DeptCode
+	})
+	sort.Slice(f.terms, func(i, j int) bool {
+		return f.terms[i].Term < f.terms[j].Term
+	})
+	log.Printf("Loaded fixtures from %s: %d courses, %d sections, %d instructors, %d active instructors, %d departments, %d terms",
+		dir, len(f.courses), len(f.sections), len(f.instructors), len(f.activeInstructors), len(f.departments), len(f.terms))
+	return f, nil
+}
+
+// Read the rows of `table` from `dir`, preferring a JSON array over NDJSON.
+func loadFixture[T any](dir, table string) ([]T, error) {
+	path := filepath.Join(dir, table+".json")
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var rows []T
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		return rows, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	path = filepath.Join(dir, table+".ndjson")
+	data, err = os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("No fixture found for table %s in %s; treating it as empty", table, dir)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var rows []T
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var row T
+		if err := json.Unmarshal(text, &row); err != nil {
+			return nil, fmt.Errorf("failed to decode %s line %d: %w", path, line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+/* ============================== DATA SOURCE ============================== */
+
+func (f *FixtureSource) Courses(args CoursesArgs) ([]Course, error) {
+	courses, err := f.filterCourses(args.CourseCodes, args.Prefix, args.Number, args.GenEds, args.Credits, args.Term)
+	if err != nil {
+		return nil, err
+	}
+	if err := sortRows(courses, args.SortBy, "courses"); err != nil {
+		return nil, err
+	}
+	return paginate(courses, args.Offset, args.Limit), nil
+}
+
+func (f *FixtureSource) MinifiedCourses(args CoursesArgs) ([]MinifiedCourse, error) {
+	courses, err := f.Courses(args)
+	if err != nil {
+		return nil, err
+	}
+	minified := make([]MinifiedCourse, len(courses))
+	for i, c := range courses {
+		minified[i] = MinifiedCourse{CourseCode: c.CourseCode, Name: c.Name}
+	}
+	return minified, nil
+}
+
+func (f *FixtureSource) CoursesWithSections(args CoursesWithSectionsArgs) ([]CourseWithSections, error) {
+	courses, err := f.filterCourses(args.CourseCodes, args.Prefix, args.Number, args.GenEds, args.Credits, args.Term)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := filterSections(
+		f.sections, args.TotalClassSize, args.OnlyOpen, args.Instructor, args.AnyInstructor, args.Term)
+	if err != nil {
+		return nil, err
+	}
+	sectionsByCourse := make(map[string][]Section)
+	for _, s := range sections {
+		sectionsByCourse[s.CourseCode] = append(sectionsByCourse[s.CourseCode], s)
+	}
+
+	// Courses without any matching sections are dropped when sections are
+	// filtered, mirroring the inner join used by SupabaseClient.
+	innerJoin := args.TotalClassSize != nil || args.OnlyOpen || args.Instructor != "" || args.AnyInstructor != nil
+	withSections := make([]CourseWithSections, 0, len(courses))
+	for _, c := range courses {
+		courseSections := sectionsByCourse[c.CourseCode]
+		if innerJoin && len(courseSections) == 0 {
+			continue
+		}
+		if courseSections == nil {
+			courseSections = []Section{}
+		}
+		withSections = append(withSections, CourseWithSections{Course: c, Sections: courseSections})
+	}
+	if err := sortRows(withSections, args.SortBy, "courses"); err != nil {
+		return nil, err
+	}
+	return paginate(withSections, args.Offset, args.Limit), nil
+}
+
+func (f *FixtureSource) Sections(args SectionsArgs) ([]Section, error) {
+	sections := f.sections
+	if args.CoursePrefix != "" {
+		// prefix takes precedence over courseCodes, as in SupabaseClient
+		sections = filter(sections, func(s Section) bool {
+			return strings.HasPrefix(s.CourseCode, args.CoursePrefix)
+		})
+	} else if args.CourseCodes != "" {
+		codes := splitList(args.CourseCodes)
+		sections = filter(sections, func(s Section) bool {
+			return slices.Contains(codes, s.CourseCode)
+		})
+	}
+	sections, err := filterSections(
+		sections, args.TotalClassSize, args.OnlyOpen, args.Instructor, args.AnyInstructor, args.Term)
+	if err != nil {
+		return nil, err
+	}
+	if err := sortRows(sections, args.SortBy, "sections"); err != nil {
+		return nil, err
+	}
+	return paginate(sections, args.Offset, args.Limit), nil
+}
+
+func (f *FixtureSource) SectionInstructors(args SectionsArgs) ([][]string, error) {
+	sections, err := f.Sections(args)
+	if err != nil {
+		return nil, err
+	}
+	names := make([][]string, len(sections))
+	for i, s := range sections {
+		names[i] = s.Instructors
+	}
+	return names, nil
+}
+
+func (f *FixtureSource) Instructors(args InstructorArgs) ([]Instructor, error) {
+	return filterInstructors(f.instructors, args, "instructors")
+}
+
+func (f *FixtureSource) ActiveInstructors(args InstructorArgs) ([]Instructor, error) {
+	return filterInstructors(f.activeInstructors, args, "active_instructors")
+}
+
+func (f *FixtureSource) CountCourses(args CoursesArgs) (int, error) {
+	args.Offset, args.Limit = 0, math.MaxInt
+	courses, err := f.Courses(args)
+	return len(courses), err
+}
+
+func (f *FixtureSource) CountCoursesWithSections(args CoursesWithSectionsArgs) (int, error) {
+	args.Offset, args.Limit = 0, math.MaxInt
+	courses, err := f.CoursesWithSections(args)
+	return len(courses), err
+}
+
+func (f *FixtureSource) CountSections(args SectionsArgs) (int, error) {
+	args.Offset, args.Limit = 0, math.MaxInt
+	sections, err := f.Sections(args)
+	return len(sections), err
+}
+
+func (f *FixtureSource) CountInstructors(args InstructorArgs) (int, error) {
+	args.Offset, args.Limit = 0, math.MaxInt
+	instructors, err := f.Instructors(args)
+	return len(instructors), err
+}
+
+func (f *FixtureSource) CountActiveInstructors(args InstructorArgs) (int, error) {
+	args.Offset, args.Limit = 0, math.MaxInt
+	instructors, err := f.ActiveInstructors(args)
+	return len(instructors), err
+}
+
+func (f *FixtureSource) Departments() ([]Department, error) {
+	return slices.Clone(f.departments), nil
+}
+
+func (f *FixtureSource) Terms() ([]Term, error) {
+	return slices.Clone(f.terms), nil
+}
+
+/* ================================ FILTERS ================================ */
+
+// Get courses matching the course-level filters shared by all course queries.
+func (f *FixtureSource) filterCourses(
+	courseCodes, prefix, number, genEds string, credits []string, term string) ([]Course, error) {
+	courses := f.courses
+	if term != "" {
+		courses = filter(courses, func(c Course) bool {
+			return c.Term == term
+		})
+	}
+	if courseCodes != "" {
+		codes := splitList(courseCodes)
+		courses = filter(courses, func(c Course) bool {
+			return slices.Contains(codes, c.CourseCode)
+		})
+	} else if prefix != "" {
+		courses = filter(courses, func(c Course) bool {
+			return strings.HasPrefix(c.CourseCode, prefix)
+		})
+	} else if number != "" {
+		// Course codes are a 4-letter department code followed by the number
+		courses = filter(courses, func(c Course) bool {
+			return len(c.CourseCode) >= 4 && strings.HasPrefix(c.CourseCode[4:], number)
+		})
+	}
+	if genEds != "" {
+		required := splitList(genEds)
+		courses = filter(courses, func(c Course) bool {
+			return containsAll(c.GenEds, required)
+		})
+	}
+	return filterRows(courses, "min_credits", credits, "courses")
+}
+
+// Get sections matching the section-level filters shared by section queries.
+func filterSections(sections []Section, totalClassSize []string, onlyOpen bool,
+	instructor string, anyInstructor []string, term string) ([]Section, error) {
+	if term != "" {
+		sections = filter(sections, func(s Section) bool {
+			return s.Term == term
+		})
+	}
+	sections, err := filterRows(sections, "total_seats", totalClassSize, "sections")
+	if err != nil {
+		return nil, err
+	}
+	if onlyOpen {
+		sections = filter(sections, func(s Section) bool {
+			return s.OpenSeats > 0
+		})
+	}
+	if instructor != "" {
+		sections = filter(sections, func(s Section) bool {
+			return slices.Contains(s.Instructors, instructor)
+		})
+	}
+	if anyInstructor != nil {
+		sections = filter(sections, func(s Section) bool {
+			return slices.ContainsFunc(s.Instructors, func(name string) bool {
+				return slices.Contains(anyInstructor, name)
+			})
+		})
+	}
+	return sections, nil
+}
+
+func filterInstructors(instructors []Instructor, args InstructorArgs, table string) ([]Instructor, error) {
+	if args.Term != "" {
+		instructors = filter(instructors, func(i Instructor) bool {
+			return i.Term == args.Term
+		})
+	}
+	if args.InstructorNames != "" {
+		names := splitList(args.InstructorNames)
+		instructors = filter(instructors, func(i Instructor) bool {
+			return slices.Contains(names, i.Name)
+		})
+	}
+	if args.InstructorSlugs != "" {
+		slugs := splitList(args.InstructorSlugs)
+		instructors = filter(instructors, func(i Instructor) bool {
+			return slices.Contains(slugs, i.Slug)
+		})
+	}
+	instructors, err := filterRows(instructors, "average_rating", args.Ratings, table)
+	if err != nil {
+		return nil, err
+	}
+	if err := sortRows(instructors, args.SortBy, table); err != nil {
+		return nil, err
+	}
+	return paginate(instructors, args.Offset, args.Limit), nil
+}
+
+/* =============================== UTILITIES =============================== */
+
+// A row of a fixture table whose columns can be filtered and sorted on.
+type fixtureRow interface {
+	// Get the value of the column `name` as a string, float64, or nil (for
+	// SQL NULL). Returns false if the column is not filterable or sortable.
+	column(name string) (any, bool)
+}
+
+func (c Course) column(name string) (any, bool) {
+	switch name {
+	case "course_code":
+		return c.CourseCode, true
+	case "name":
+		return c.Name, true
+	case "min_credits":
+		return float64(c.MinCredits), true
+	case "max_credits":
+		return nullableInt(c.MaxCredits), true
+	case "description":
+		if c.Description == nil {
+			return nil, true
+		}
+		return *c.Description, true
+	case "term":
+		return c.Term, true
+	}
+	return nil, false
+}
+
+func (s Section) column(name string) (any, bool) {
+	switch name {
+	case "course_code":
+		return s.CourseCode, true
+	case "sec_code":
+		return s.SecCode, true
+	case "open_seats":
+		return float64(s.OpenSeats), true
+	case "total_seats":
+		return float64(s.TotalSeats), true
+	case "waitlist":
+		return float64(s.Waitlist), true
+	case "holdfile":
+		return nullableInt(s.Holdfile), true
+	case "term":
+		return s.Term, true
+	}
+	return nil, false
+}
+
+func (i Instructor) column(name string) (any, bool) {
+	switch name {
+	case "slug":
+		return i.Slug, true
+	case "name":
+		return i.Name, true
+	case "average_rating":
+		if i.AverageRating == nil {
+			return nil, true
+		}
+		return *i.AverageRating, true
+	}
+	return nil, false
+}
+
+func nullableInt(v *int) any {
+	if v == nil {
+		return nil
+	}
+	return float64(*v)
+}
+
+// Build an error resembling the one PostgREST returns for a bad query.
+func fixtureQueryError(format string, a ...any) *UpstreamError {
+	body, _ := json.Marshal(map[string]string{"message": fmt.Sprintf(format, a...)})
+	header := http.Header{}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	return &UpstreamError{Status: http.StatusBadRequest, Header: header, Body: body}
+}
+
+// Keep only rows for which `column` satisfies every condition in `conds`. Each
+// condition is a PostgREST-style comparison such as `gt.3` or `neq.0`.
+func filterRows[T fixtureRow](rows []T, column string, conds []string, table string) ([]T, error) {
+	for _, cond := range conds {
+		op, operand, _ := strings.Cut(cond, ".")
+		var evalErr error
+		rows = filter(rows, func(row T) bool {
+			value, _ := row.column(column)
+			ok, err := compareOperand(value, op, operand)
+			if err != nil && evalErr == nil {
+				evalErr = err
+			}
+			return ok
+		})
+		if evalErr != nil {
+			return nil, fixtureQueryError("invalid filter on %s.%s: %s", table, column, evalErr)
+		}
+	}
+	return rows, nil
+}
+
+// Evaluate `value <op> operand`. NULL values never satisfy a comparison.
+func compareOperand(value any, op, operand string) (bool, error) {
+	var cmp int
+	switch v := value.(type) {
+	case nil:
+		if !slices.Contains([]string{"eq", "neq", "lt", "lte", "gt", "gte"}, op) {
+			return false, fmt.Errorf("unknown operator %q", op)
+		}
+		return false, nil
+	case float64:
+		n, err := strconv.ParseFloat(operand, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid number %q", operand)
+		}
+		cmp = compareValues(v, n)
+	case string:
+		cmp = strings.Compare(v, operand)
+	}
+	switch op {
+	case "eq":
+		return cmp == 0, nil
+	case "neq":
+		return cmp != 0, nil
+	case "lt":
+		return cmp < 0, nil
+	case "lte":
+		return cmp <= 0, nil
+	case "gt":
+		return cmp > 0, nil
+	case "gte":
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// Compare two non-nil column values of the same type.
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// Sort rows in place by a PostgREST-style order string, such as
+// `name.asc,min_credits.desc.nullslast`. As in Postgres, NULLs sort last in
+// ascending order and first in descending order unless specified otherwise.
+func sortRows[T fixtureRow](rows []T, order string, table string) error {
+	if order == "" {
+		return nil
+	}
+	type sortTerm struct {
+		column     string
+		desc       bool
+		nullsFirst bool
+	}
+	terms := []sortTerm{}
+	for _, part := range strings.Split(order, ",") {
+		fields := strings.Split(part, ".")
+		term := sortTerm{column: fields[0]}
+		explicitNulls := false
+		for _, modifier := range fields[1:] {
+			switch modifier {
+			case "asc":
+				term.desc = false
+			case "desc":
+				term.desc = true
+			case "nullsfirst":
+				term.nullsFirst, explicitNulls = true, true
+			case "nullslast":
+				term.nullsFirst, explicitNulls = false, true
+			default:
+				return fixtureQueryError("invalid order modifier %q in %q", modifier, part)
+			}
+		}
+		if !explicitNulls {
+			term.nullsFirst = term.desc
+		}
+		var zero T
+		if _, ok := zero.column(term.column); !ok {
+			return fixtureQueryError("column %s.%s does not exist", table, term.column)
+		}
+		terms = append(terms, term)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, term := range terms {
+			a, _ := rows[i].column(term.column)
+			b, _ := rows[j].column(term.column)
+			if a == nil || b == nil {
+				if a == nil && b == nil {
+					continue
+				}
+				return (a == nil) == term.nullsFirst
+			}
+			cmp := compareValues(a, b)
+			if cmp == 0 {
+				continue
+			}
+			return (cmp < 0) != term.desc
+		}
+		return false
+	})
+	return nil
+}
+
+// Get the page of `rows` starting at `offset` with at most `limit` rows.
+func paginate[T any](rows []T, offset, limit int) []T {
+	start := min(offset, len(rows))
+	end := start + min(limit, len(rows)-start)
+	return slices.Clone(rows[start:end])
+}
+
+// Get the elements of `rows` that satisfy `keep`, without modifying `rows`.
+func filter[T any](rows []T, keep func(T) bool) []T {
+	kept := make([]T, 0, len(rows))
+	for _, row := range rows {
+		if keep(row) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// Split a comma-separated list, trimming whitespace around each element.
+func splitList(list string) []string {
+	elems := strings.Split(list, ",")
+	for i, e := range elems {
+		elems[i] = strings.TrimSpace(e)
+	}
+	return elems
+}
+
+// Check whether `values` contains every element of `required`.
+func containsAll(values, required []string) bool {
+	for _, r := range required {
+		if !slices.Contains(values, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -20,8 +23,25 @@ const (
 	instructorsTTL time.Duration = 12 * time.Hour
 	departmentsTTL time.Duration = 2 * time.Hour
 	sectionsTTL    time.Duration = 15 * time.Minute
+	termsTTL       time.Duration = 2 * time.Hour
 )
 
+// A Server handles API requests, retrieving data from a DataSource and
+// caching responses in an LRUCache.
+type Server struct {
+	source  DataSource
+	cache   *LRUCache
+	flights *flightGroup
+	router  *gin.Engine
+
+	// Data derived from whole tables, cached apart from responses
+	terms              *derivedCache[[]Term]
+	searchIndexes      *derivedCache[*searchIndex]
+	instructors        *derivedCache[[]Instructor]
+	sectionInstructors *derivedCache[[]string]
+	prerequisiteGraphs *derivedCache[prerequisiteGraph]
+}
+
 /* ================================= ARGS ================================== */
 // For all argument structs, the first character of a field must be upper-case
 // so it can be written to when parsing query args.
@@ -41,19 +61,29 @@ type CoursesArgs struct {
 	GenEds string `form:"genEds"`
 
 	// Conditions for credits; for example, eq.3
-	Credits []string `form:"credits"`
+	Credits []string `form:"credits" binding:"omitempty,comparisons=int"`
+
+	// The term (semester) to get data for, as YYYYMM; for example, 202601.
+	// Default value: the current default term
+	Term string `form:"term" binding:"omitempty,len=6,numeric"`
 
 	// Number of courses to return per page.
 	// Default value: 100; Maximum value: 500
-	Limit uint16 `form:"limit" binding:"omitempty,min=1,max=500"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=500"`
 
 	// The offset of courses to view. For example, offset=30 will return
 	// courses starting at the 30th result.
 	// Default value: 0
-	Offset uint16 `form:"offset"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+
+	// Cursor pagination and total counts
+	PageArgs
 
 	// String of columns to sort by
-	SortBy string `form:"sortBy"`
+	SortBy string `form:"sortBy" binding:"omitempty,sortcolumns=course_code name min_credits max_credits description term"`
+
+	// Include the structured form of each course's conditions if true
+	ParsedConditions bool `form:"parsedConditions"`
 }
 
 func (c *CoursesArgs) setDefaults() {
@@ -77,10 +107,10 @@ type CoursesWithSectionsArgs struct {
 	GenEds string `form:"genEds"`
 
 	// Conditions for credits; for example, eq.3
-	Credits []string `form:"credits"`
+	Credits []string `form:"credits" binding:"omitempty,comparisons=int"`
 
 	// Total class size conditions; for example, lt.30
-	TotalClassSize []string `form:"totalClassSize"`
+	TotalClassSize []string `form:"totalClassSize" binding:"omitempty,comparisons=int"`
 
 	// Only open sections if true
 	OnlyOpen bool `form:"onlyOpen"`
@@ -88,17 +118,44 @@ type CoursesWithSectionsArgs struct {
 	// Instructor name filter (case sensitive, exact contains match)
 	Instructor string `form:"instructor"`
 
+	// How to match the instructor filter: exact, ci (case-insensitive, or by
+	// last name), or fuzzy (any part of the name, allowing typos)
+	// Default value: exact
+	InstructorMatch string `form:"instructorMatch" binding:"omitempty,oneof=exact ci fuzzy"`
+
+	// Only courses with sections taught by any of these instructors. Only set
+	// internally, not from query args.
+	AnyInstructor []string `form:"-"`
+
+	// Filters on the days and times sections meet
+	MeetingFilter
+
+	// Set to instructors to link each section's instructors to their
+	// PlanetTerp slugs and ratings
+	Expand string `form:"expand" binding:"omitempty,oneof=instructors"`
+
+	// Only sections whose instructors have at least this average rating
+	MinInstructorRating float64 `form:"minInstructorRating" binding:"omitempty,gte=0,lte=5"`
+
+	// The term (semester) to get data for, as YYYYMM; for example, 202601.
+	// Default value: the current default term
+	Term string `form:"term" binding:"omitempty,len=6,numeric"`
+
 	// Number of courses to return per page.
 	// Default value: 100; Maximum value: 500
-	Limit uint16 `form:"limit" binding:"omitempty,min=1,max=500"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=500"`
 
 	// The offset of courses to view. For example, offset=30 will return
 	// courses starting at the 30th result.
 	// Default value: 0
-	Offset uint16 `form:"offset"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
 
-	// String of columns to sort by
-	SortBy string `form:"sortBy"`
+	// Cursor pagination and total counts
+	PageArgs
+
+	// String of columns to sort by; instructor_rating sorts by the best
+	// section instructor rating of each course, and sorts its sections too
+	SortBy string `form:"sortBy" binding:"omitempty,sortcolumns=course_code name min_credits max_credits description term instructor_rating"`
 }
 
 func (c *CoursesWithSectionsArgs) setDefaults() {
@@ -116,26 +173,53 @@ type SectionsArgs struct {
 	// all sections for all MATH courses.
 	CoursePrefix string `form:"prefix"`
 
+	// The term (semester) to get data for, as YYYYMM; for example, 202601.
+	// Default value: the current default term
+	Term string `form:"term" binding:"omitempty,len=6,numeric"`
+
 	// Number of sections to return per page.
 	// Default value: 100; Maximum value: 500
-	Limit uint16 `form:"limit" binding:"omitempty,min=1,max=500"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=500"`
 
 	// The offset of sections to view. For example, offset=30 will return
 	// sections starting at the 30th result.
 	// Default value: 0
-	Offset uint16 `form:"offset"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
 
-	// String of columns to sort by
-	SortBy string `form:"sortBy"`
+	// Cursor pagination and total counts
+	PageArgs
+
+	// String of columns to sort by; instructor_rating sorts by the average
+	// rating of each section's instructors
+	SortBy string `form:"sortBy" binding:"omitempty,sortcolumns=course_code sec_code open_seats total_seats waitlist holdfile term instructor_rating"`
 
 	// Total class size conditions; for example, lt.30
-	TotalClassSize []string `form:"totalClassSize"`
+	TotalClassSize []string `form:"totalClassSize" binding:"omitempty,comparisons=int"`
 
 	// Only open sections if true
 	OnlyOpen bool `form:"onlyOpen"`
 
 	// Instructor name filter (case sensitive, exact contains match)
 	Instructor string `form:"instructor"`
+
+	// How to match the instructor filter: exact, ci (case-insensitive, or by
+	// last name), or fuzzy (any part of the name, allowing typos)
+	// Default value: exact
+	InstructorMatch string `form:"instructorMatch" binding:"omitempty,oneof=exact ci fuzzy"`
+
+	// Filters on the days and times sections meet
+	MeetingFilter
+
+	// Set to instructors to link each section's instructors to their
+	// PlanetTerp slugs and ratings
+	Expand string `form:"expand" binding:"omitempty,oneof=instructors"`
+
+	// Only sections whose instructors have at least this average rating
+	MinInstructorRating float64 `form:"minInstructorRating" binding:"omitempty,gte=0,lte=5"`
+
+	// Only sections taught by any of these instructors. Only set internally,
+	// not from query args.
+	AnyInstructor []string `form:"-"`
 }
 
 func (s *SectionsArgs) setDefaults() {
@@ -153,19 +237,31 @@ type InstructorArgs struct {
 	InstructorSlugs string `form:"instructorSlugs"`
 
 	// Conditions for instructor ratings; for example, gt.3.5
-	Ratings []string `form:"ratings"`
+	Ratings []string `form:"ratings" binding:"omitempty,comparisons"`
+
+	// The term (semester) to get active instructors for, as YYYYMM; only
+	// supported when getting active instructors, or when including sections,
+	// in which case it is the term to get sections for.
+	// Default value: the current default term
+	Term string `form:"term" binding:"omitempty,len=6,numeric"`
+
+	// Set to sections to include the sections each instructor teaches
+	Include string `form:"include" binding:"omitempty,oneof=sections"`
 
 	// Number of sections to return per page.
 	// Default value: 100; Maximum value: 500
-	Limit uint16 `form:"limit" binding:"omitempty,min=1,max=500"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=500"`
 
 	// The offset of sections to view. For example, offset=30 will return
 	// sections starting at the 30th result.
 	// Default value: 0
-	Offset uint16 `form:"offset"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+
+	// Cursor pagination and total counts
+	PageArgs
 
 	// String of columns to sort by
-	SortBy string `form:"sortBy"`
+	SortBy string `form:"sortBy" binding:"omitempty,sortcolumns=slug name average_rating"`
 }
 
 func (i *InstructorArgs) setDefaults() {
@@ -174,13 +270,27 @@ func (i *InstructorArgs) setDefaults() {
 	}
 }
 
+// Arguments for getting a single course, section, or instructor.
+type ResourceArgs struct {
+	// The term (semester) to get data for, as YYYYMM; for example, 202601.
+	// Not supported when getting a single instructor.
+	// Default value: the current default term
+	Term string `form:"term" binding:"omitempty,len=6,numeric"`
+
+	// Include the structured form of the course's conditions if true; only
+	// supported for courses
+	ParsedConditions bool `form:"parsedConditions"`
+}
+
 /* =============================== UTILITIES =============================== */
 
 // Takes the error from a failed query argument validation/binding and sends a
-// message to the caller listing any missing or invalid args.
+// problem to the caller listing any missing or invalid args.
 func sendInvalidArgsError(ctx *gin.Context, argsType reflect.Type, path string, err error) {
 	missing := []string{}
 	invalid := []string{}
+	params := []InvalidParam{}
+	reasons := []string{}
 
 	var valErrs validator.ValidationErrors
 	if errors.As(err, &valErrs) {
@@ -188,11 +298,16 @@ func sendInvalidArgsError(ctx *gin.Context, argsType reflect.Type, path string,
 		for _, e := range errs {
 			fieldName := e.Field()
 			if field, ok := argsType.FieldByName(fieldName); ok {
+				name := field.Tag.Get("form")
 				if e.Tag() == "required" {
-					missing = append(missing, field.Tag.Get("form"))
+					missing = append(missing, name)
+				} else if check, ok := argValidators[e.Tag()]; ok {
+					invalid = append(invalid, fmt.Sprintf("%s: %s", fieldName, check(e.Value(), e.Param())))
 				} else {
 					invalid = append(invalid, fmt.Sprintf("%s: %s", fieldName, field.Tag.Get("binding")))
 				}
+				params = append(params, InvalidParam{Name: name, Reason: validationReason(e)})
+				reasons = append(reasons, fmt.Sprintf("%s %s", name, validationReason(e)))
 			} else {
 				sendInternalError(ctx, path, fmt.Errorf("failed to identify fieldName: %s", fieldName))
 				return
@@ -208,27 +323,23 @@ func sendInvalidArgsError(ctx *gin.Context, argsType reflect.Type, path string,
 		}
 		errMsg := strings.Join(errMsgList, "; ")
 		log.Printf("Received GET %s but was missing arguments: %s", path, errMsg)
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": errMsg,
-		})
+		problem := newInvalidParams(strings.Join(reasons, "; "), params...)
+		problem.legacyError = errMsg
+		sendProblem(ctx, problem)
 		return
 	}
 
 	// Non-validation bind errors (e.g., strconv.NumError: value out of range)
 	log.Printf("Received GET %s with malformed query params: %v", path, err)
-	ctx.JSON(http.StatusBadRequest, gin.H{
-		"error": "Malformed query parameters. Check types and ranges.",
-	})
+	sendProblem(ctx, newInvalidParams("Malformed query parameters. Check types and ranges."))
 }
 
 // Takes an internal error and logs it for devs; sends a generic internal error
-// message to an API caller. This allows for devs to see internal errors, but
+// problem to an API caller. This allows for devs to see internal errors, but
 // avoids exposing internal data to callers.
 func sendInternalError(ctx *gin.Context, path string, err error) {
-	log.Printf("Internal error while handling %s: %s", path, err)
-	ctx.JSON(http.StatusInternalServerError, gin.H{
-		"error": "Internal server error.",
-	})
+	log.Printf("Internal error while handling %s (request %s): %s", path, ctx.GetString(requestIDKey), err)
+	sendProblem(ctx, newProblem(problemInternal, http.StatusInternalServerError, "Internal server error."))
 }
 
 func buildCacheKey(r *http.Request) string {
@@ -253,6 +364,9 @@ func buildCacheKey(r *http.Request) string {
 }
 
 func writePayload(ctx *gin.Context, payload *cachedPayload, path string) bool {
+	payload = useStaleOnError(ctx, payload)
+	finishFlight(ctx, payload)
+	payload = stampProblemPayload(ctx, payload)
 	header := ctx.Writer.Header()
 	replacedKeys := make(map[string]struct{}, len(payload.header))
 	for k := range payload.header {
@@ -270,6 +384,11 @@ func writePayload(ctx *gin.Context, payload *cachedPayload, path string) bool {
 			header.Add(canonicalKey, v)
 		}
 	}
+	if payload.status == http.StatusOK && isNotModified(ctx.Request, header) {
+		ctx.Status(http.StatusNotModified)
+		ctx.Writer.WriteHeaderNow()
+		return true
+	}
 	ctx.Status(payload.status)
 	if _, err := ctx.Writer.Write(payload.body); err != nil {
 		_ = ctx.Error(err)
@@ -279,124 +398,491 @@ func writePayload(ctx *gin.Context, payload *cachedPayload, path string) bool {
 	return true
 }
 
-func buildPayloadFromResponse(res *http.Response) (*cachedPayload, error) {
-	defer res.Body.Close()
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
+// Encode `result` as JSON and wrap it in a payload with the given status.
+func buildJSONPayload(status int, result any) (*cachedPayload, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
 		return nil, err
 	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	payload := &cachedPayload{
+		status: status,
+		header: header,
+		body:   bytes.TrimSuffix(buf.Bytes(), []byte("\n")),
+	}
+	if status == http.StatusOK {
+		setValidators(payload, time.Now())
+	}
+	return payload, nil
+}
+
+// Build a payload from an upstream error, so the caller receives the same
+// status and body that the backing store returned.
+func buildPayloadFromUpstreamError(upstreamErr *UpstreamError) *cachedPayload {
 	return &cachedPayload{
-		status: res.StatusCode,
-		header: filterHeadersForCaching(res.Header),
-		body:   body,
-	}, nil
+		status: upstreamErr.Status,
+		header: filterHeadersForCaching(upstreamErr.Header),
+		body:   upstreamErr.Body,
+	}
 }
 
-func (client SupabaseClient) serveFromCache(ctx *gin.Context, path, key string) bool {
-	payload, ok := client.cache.Get(key)
-	if !ok {
-		log.Printf("Cache MISS for GET %s with key %s", path, key)
+// Send the cached payload for `key` to the caller if there is one. Stale
+// payloads are sent while a fresh one is fetched in the background. On a miss,
+// if another request is already fetching the payload for `key`, waits for it
+// and sends the same payload. Otherwise, returns false and the caller must
+// fetch and send the payload, which is then sent to any requests waiting for
+// it; if fetching fails, an expired payload is sent instead if there is one.
+func (server Server) serveFromCache(ctx *gin.Context, path, key string) bool {
+	if f, ok := ctx.Request.Context().Value(revalidationKey{}).(*flight); ok {
+		// Refreshing a stale payload for another request; see `revalidate`
+		log.Printf("Cache REVALIDATE for GET %s with key %s", path, key)
+		ctx.Set(flightKey, f)
+		server.setStaleFallback(ctx, key, server.cache.Lookup(key))
 		return false
 	}
-	if writePayload(ctx, payload, path) {
-		log.Printf("Cache HIT and served GET %s from cache with status %d", path, payload.status)
+	for {
+		lookup := server.cache.Lookup(key)
+		switch lookup.state {
+		case cacheFresh:
+			if writePayload(ctx, withAge(lookup.payload, lookup.age), path) {
+				log.Printf("Cache HIT and served GET %s from cache with status %d", path, lookup.payload.status)
+			}
+			return true
+		case cacheStale:
+			if lookup.revalidate {
+				server.revalidate(ctx, key)
+			}
+			cacheMetrics.Add(metricStale, 1)
+			warning := warningStale
+			if lookup.failed {
+				warning = warningRevalidateFailed
+			}
+			if writePayload(ctx, withStaleHeaders(lookup.payload, lookup.age, warning), path) {
+				log.Printf("Cache STALE and served GET %s from cache with status %d", path, lookup.payload.status)
+			}
+			return true
+		}
+		f, leader := server.flights.join(key)
+		if leader {
+			log.Printf("Cache MISS for GET %s with key %s", path, key)
+			cacheMetrics.Add(metricFetches, 1)
+			ctx.Set(flightKey, f)
+			server.setStaleFallback(ctx, key, lookup)
+			return false
+		}
+		select {
+		case <-f.done:
+		case <-ctx.Request.Context().Done():
+			// The caller is gone, so there's nobody to respond to
+			return true
+		}
+		if f.payload != nil {
+			cacheMetrics.Add(metricDeduplicated, 1)
+			if writePayload(ctx, f.payload, path) {
+				log.Printf("Coalesced GET %s with a concurrent request with status %d", path, f.payload.status)
+			}
+			return true
+		}
+		// The leader didn't send a payload, so try again
 	}
+}
 
-	return true
+// Keep the payload found by `lookup`, if any, to send if fetching a fresh
+// payload for `key` fails.
+func (server Server) setStaleFallback(ctx *gin.Context, key string, lookup cacheLookup) {
+	if lookup.state != cacheMiss {
+		ctx.Set(staleFallbackKey, &staleFallback{
+			cache: server.cache, key: key, payload: lookup.payload, age: lookup.age,
+		})
+	}
+}
+
+// Send the result of a DataSource query to the caller and cache it. Upstream
+// errors are passed through and NotFoundErrors are sent as a 404; any other
+// error is sent as an internal error. Results with a `ttl` of 0 or of requests
+// other than GET, whose results can depend on the request body, aren't cached,
+// and are sent as not cacheable by browsers and CDNs either.
+func (server Server) writeAndCacheResult(
+	ctx *gin.Context, result any, err error, path, key string, ttl time.Duration) {
+	var payload *cachedPayload
+	var upstreamErr *UpstreamError
+	var notFoundErr *NotFoundError
+	if errors.As(err, &upstreamErr) {
+		if payload, err = buildUpstreamErrorPayload(ctx, upstreamErr); err != nil {
+			sendInternalError(ctx, path, err)
+			return
+		}
+	} else if errors.As(err, &notFoundErr) {
+		problem := newProblem(problemNotFound, http.StatusNotFound, notFoundErr.Message)
+		if payload, err = buildProblemPayload(ctx, problem); err != nil {
+			sendInternalError(ctx, path, err)
+			return
+		}
+	} else if err != nil {
+		sendInternalError(ctx, path, err)
+		return
+	} else if payload, err = buildJSONPayload(http.StatusOK, wrapResult(ctx, result, nil)); err != nil {
+		sendInternalError(ctx, path, err)
+		return
+	}
+	cacheable := ttl > 0 && ctx.Request.Method == http.MethodGet
+	if payload.status == http.StatusOK && cacheable {
+		setCacheControl(payload, ttl)
+	} else {
+		setNoStore(payload)
+	}
+	if cacheable && payload.status < http.StatusInternalServerError {
+		server.cache.Set(key, payload, ttl)
+	}
+	if writePayload(ctx, payload, path) {
+		log.Printf("Successfully handled %s %s with status %d", ctx.Request.Method, path, payload.status)
+	}
 }
 
-func (client SupabaseClient) writeAndCacheResponse(ctx *gin.Context, res *http.Response, path, key string, ttl time.Duration) {
-	statusText := res.Status
-	payload, err := buildPayloadFromResponse(res)
+// Send a page of results to the caller and cache it, as with
+// writeAndCacheResult, along with headers linking to the pages around it.
+func (server Server) writeAndCachePage(
+	ctx *gin.Context, result any, p page, err error, path, key string, ttl time.Duration) {
+	if err != nil {
+		server.writeAndCacheResult(ctx, result, err, path, key, ttl)
+		return
+	}
+	p.size = resultLen(result)
+	payload, err := buildJSONPayload(http.StatusOK, wrapResult(ctx, result, &p))
 	if err != nil {
 		sendInternalError(ctx, path, err)
 		return
 	}
+	for k, values := range p.header(ctx.Request) {
+		payload.header[k] = values
+	}
+	setCacheControl(payload, ttl)
+	server.cache.Set(key, payload, ttl)
 	if writePayload(ctx, payload, path) {
-		log.Printf("Successfully handled GET %s with status %s", path, statusText)
+		log.Printf("Successfully handled %s %s with status %d", ctx.Request.Method, path, payload.status)
+	}
+}
+
+// Get the list of available terms, from the cache if possible. If the data
+// source has no terms table, data is treated as being for a single, implicit
+// term and no terms are returned.
+func (server Server) getTerms() ([]Term, error) {
+	return server.terms.get("", func() ([]Term, error) {
+		terms, err := server.source.Terms()
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) {
+			log.Printf("Failed to get terms, so serving data without terms: %s", err)
+			return []Term{}, nil
+		} else if err != nil {
+			return nil, err
+		} else if terms == nil {
+			terms = []Term{}
+		}
+		return terms, nil
+	})
+}
+
+// Set `term` to the default term if it is empty, or check that it is an
+// available term otherwise. Sends an error to the caller and returns false if
+// the term is not available.
+func (server Server) resolveTerm(ctx *gin.Context, path string, term *string) bool {
+	terms, err := server.getTerms()
+	if err != nil {
+		sendInternalError(ctx, path, err)
+		return false
 	}
-	if res.StatusCode < http.StatusInternalServerError {
-		client.cache.Set(key, payload, ttl)
+	for _, t := range terms {
+		if (*term == "" && t.IsDefault) || (*term != "" && t.Term == *term) {
+			*term = t.Term
+			ctx.Set(resolvedTermKey, *term)
+			return true
+		}
 	}
+	if *term == "" {
+		// No default term; serve data across all terms
+		return true
+	}
+	sendProblem(ctx, newInvalidParams(
+		fmt.Sprintf("Unknown term %s; see /v0/terms for available terms", *term),
+		InvalidParam{Name: "term", Reason: "is not an available term"},
+	))
+	return false
+}
+
+// Resolve an instructor filter to the names of the instructors teaching
+// sections in `term` when matching with `mode` other than exact, moving the
+// names to `anyInstructor` and clearing `instructor`. Returns false if no
+// instructors match, in which case no sections can match either.
+func (server Server) resolveInstructor(instructor *string, anyInstructor *[]string, mode, term string) (bool, error) {
+	if *instructor == "" || mode == "" || mode == instructorMatchExact {
+		return true, nil
+	}
+	names, err := server.getSectionInstructorNames(term)
+	if err != nil {
+		return false, err
+	}
+	matches := matchInstructorNames(*instructor, names, mode)
+	if len(matches) == 0 {
+		return false, nil
+	}
+	*instructor, *anyInstructor = "", matches
+	return true, nil
+}
+
+// Parse the instructor rating filters of a sections request, removing the
+// instructor_rating column from `sortBy`. Sends an error to the caller and
+// returns false if they are invalid.
+func parseSectionRatings(ctx *gin.Context, sortBy *string, minRating float64) (sectionRatings, bool) {
+	rest, sorted, desc, err := parseRatingSort(*sortBy)
+	if err != nil {
+		sendProblem(ctx, newInvalidParamError(fmt.Sprintf("Invalid sortBy: %s", err), "sortBy", err))
+		return sectionRatings{}, false
+	}
+	*sortBy = rest
+	return sectionRatings{min: minRating, sorted: sorted, desc: desc}, true
+}
+
+// Check that the filters of a sections request applied after fetching, those in
+// `filter` and `ratings`, are narrowed to a list of courses or at least a
+// department by `courseCodes` or `prefix`, so they don't read every section in
+// the term. Returns a problem naming the filters and false if not.
+func checkFilterScope(filter MeetingFilter, ratings sectionRatings, courseCodes, prefix string) (Problem, bool) {
+	if isFilterScoped(courseCodes, prefix) {
+		return Problem{}, true
+	}
+	given := filter.params()
+	if ratings.min > 0 {
+		given = append(given, "minInstructorRating")
+	}
+	if ratings.sorted {
+		given = append(given, "sortBy")
+	}
+	if len(given) == 0 {
+		return Problem{}, true
+	}
+	labels := slices.Clone(given)
+	params := make([]InvalidParam, len(given))
+	for i, name := range given {
+		reason := "requires courseCodes or a prefix of at least a department code"
+		if name == "sortBy" {
+			labels[i] = "sortBy=" + instructorRatingColumn
+			reason = instructorRatingColumn + " " + reason
+		}
+		params[i] = InvalidParam{Name: name, Reason: reason}
+	}
+	detail := fmt.Sprintf("%s can only be used with courseCodes or a prefix of at least a department code, such as prefix=CMSC",
+		strings.Join(labels, ", "))
+	return newInvalidParams(detail, params...), false
+}
+
+// Check that at most one of the courseCodes, prefix, and number filters is
+// given. Returns a problem naming the filters that were given together and
+// false if not.
+func checkCourseFilters(courseCodes, prefix, number string) (Problem, bool) {
+	given := []string{}
+	for _, filter := range []struct{ name, value string }{
+		{"courseCodes", courseCodes}, {"prefix", prefix}, {"number", number},
+	} {
+		if filter.value != "" {
+			given = append(given, filter.name)
+		}
+	}
+	var detail string
+	switch len(given) {
+	case 0, 1:
+		return Problem{}, true
+	case 2:
+		detail = fmt.Sprintf("Cannot specify both %s and %s", given[0], given[1])
+	default:
+		detail = "Cannot specify courseCodes, prefix, and number simultaneously"
+	}
+	params := make([]InvalidParam, len(given))
+	for i, name := range given {
+		others := slices.Delete(slices.Clone(given), i, i+1)
+		params[i] = InvalidParam{Name: name, Reason: "cannot be used with " + strings.Join(others, " or ")}
+	}
+	return newInvalidParams(detail, params...), false
 }
 
 // General method for getting courses and sending the response to the caller.
-func (client SupabaseClient) getCoursesAndSendResponse(
-	ctx *gin.Context, columns []string, path string, ttl time.Duration) {
+// `fetch` retrieves the courses from the data source once args are parsed, and
+// `count` counts them if a count is requested.
+func (server Server) getCoursesAndSendResponse(ctx *gin.Context, path string, ttl time.Duration,
+	fetch func(CoursesArgs) (any, error), count func(CoursesArgs) (int, error)) {
 	// Parse args
 	var args CoursesArgs
 	if err := ctx.ShouldBindQuery(&args); err != nil {
 		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, err)
 		return
 	}
-	if args.CourseCodes != "" && args.Prefix != "" && args.Number != "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": "Cannot specify courseCodes, prefix, and number simultaneously",
-		})
+	if problem, ok := checkCourseFilters(args.CourseCodes, args.Prefix, args.Number); !ok {
+		sendProblem(ctx, problem)
 		return
 	}
-	if args.CourseCodes != "" && args.Prefix != "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": "Cannot specify both courseCodes and prefix",
-		})
+	args.setDefaults()
+	if !args.resolveCursor(ctx, &args.Offset) {
 		return
 	}
-	if args.CourseCodes != "" && args.Number != "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": "Cannot specify both courseCodes and number",
-		})
+
+	key := buildCacheKey(ctx.Request)
+	if server.serveFromCache(ctx, path, key) {
 		return
 	}
-	if args.Prefix != "" && args.Number != "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": "Cannot specify both prefix and number",
-		})
+	if !server.resolveTerm(ctx, path, &args.Term) {
+		return
+	}
+
+	// Get data from DB
+	courses, err := fetch(args)
+	total := -1
+	if err == nil {
+		total, err = args.countTotal(func() (int, error) { return count(args) })
+	}
+	server.writeAndCachePage(ctx, courses, page{offset: args.Offset, limit: args.Limit, total: total},
+		err, path, key, ttl)
+}
+
+// General method for getting instructors and sending the response to the caller.
+// `fetch` retrieves the instructors from the data source once args are parsed,
+// and `count` counts them if a count is requested; `byTerm` is true if the
+// instructors can be filtered by term.
+func (server Server) getInstructorsAndSendResponse(ctx *gin.Context, path string, ttl time.Duration,
+	byTerm bool, fetch func(InstructorArgs) ([]Instructor, error), count func(InstructorArgs) (int, error)) {
+	var args InstructorArgs
+	if err := ctx.ShouldBindQuery(&args); err != nil {
+		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, err)
+		return
+	}
+	if args.InstructorNames != "" && args.InstructorSlugs != "" {
+		problem := newInvalidParams("Cannot specify both instructorNames and instructorSlugs",
+			InvalidParam{Name: "instructorNames", Reason: "cannot be used with instructorSlugs"},
+			InvalidParam{Name: "instructorSlugs", Reason: "cannot be used with instructorNames"},
+		)
+		problem.legacyError = "Malformed query parameters. Check types and ranges."
+		sendProblem(ctx, problem)
+		return
+	}
+	includeSections := args.Include == "sections"
+	if !byTerm && !includeSections && args.Term != "" {
+		sendProblem(ctx, newInvalidParams(
+			"Cannot specify term for instructors; use /v0/instructors/active",
+			InvalidParam{Name: "term", Reason: "is not supported for instructors"},
+		))
 		return
 	}
 	args.setDefaults()
+	if !args.resolveCursor(ctx, &args.Offset) {
+		return
+	}
 
 	key := buildCacheKey(ctx.Request)
-	if client.serveFromCache(ctx, path, key) {
+	if server.serveFromCache(ctx, path, key) {
 		return
 	}
-
-	// Get data from DB
-	res, err := client.getCourses(args, columns)
-	if err != nil {
-		sendInternalError(ctx, path, err)
+	if (byTerm || includeSections) && !server.resolveTerm(ctx, path, &args.Term) {
 		return
 	}
 
-	client.writeAndCacheResponse(ctx, res, path, key, ttl)
+	// Get data from DB. Only active instructors are filtered by term; for
+	// other instructors, the term is only used to get sections.
+	sectionsTerm := args.Term
+	if !byTerm {
+		args.Term = ""
+	}
+	instructors, err := fetch(args)
+	p := page{offset: args.Offset, limit: args.Limit, total: -1}
+	if err == nil {
+		p.total, err = args.countTotal(func() (int, error) { return count(args) })
+	}
+	if err != nil || !includeSections {
+		server.writeAndCachePage(ctx, instructors, p, err, path, key, ttl)
+		return
+	}
+	joined, err := withSections(server.source, instructors, sectionsTerm)
+	server.writeAndCachePage(ctx, joined, p, err, path, key, min(ttl, sectionsTTL))
 }
 
-// General method for getting instructors and sending the response to the caller.
-func (client SupabaseClient) getInstructorsAndSendResponse(
-	ctx *gin.Context, path string, table string, ttl time.Duration) {
-	var args InstructorArgs
+// General method for traversing the prerequisite graph from the course in the
+// path and sending the response to the caller. `traverse` computes the result
+// once the graph is loaded and the course is known to exist.
+func (server Server) traversePrerequisitesAndSendResponse(ctx *gin.Context, path string,
+	traverse func(graph prerequisiteGraph, code string, args PrerequisiteArgs) any) {
+	var args PrerequisiteArgs
 	if err := ctx.ShouldBindQuery(&args); err != nil {
 		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, err)
 		return
 	}
-	if args.InstructorNames != "" && args.InstructorSlugs != "" {
-		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, errors.New("cannot specify both instructorNames and instructorSlugs"))
+	code, ok := courseCodeParam(ctx)
+	if !ok {
 		return
 	}
 	args.setDefaults()
 
 	key := buildCacheKey(ctx.Request)
-	if client.serveFromCache(ctx, path, key) {
+	if server.serveFromCache(ctx, path, key) {
+		return
+	}
+	if !server.resolveTerm(ctx, path, &args.Term) {
 		return
 	}
 
-	// Get data from DB
-	res, err := client.getInstructors(args, table)
-	if err != nil {
-		sendInternalError(ctx, path, err)
+	graph, err := server.getPrerequisiteGraph(args.Term)
+	var result any
+	if _, ok := graph[code]; err == nil && !ok {
+		err = &NotFoundError{Message: fmt.Sprintf("Course %s not found", code)}
+	} else if err == nil {
+		result = traverse(graph, code, args)
+	}
+	server.writeAndCacheResult(ctx, result, err, path, key, coursesTTL)
+}
+
+// Get the course code from the path, in upper case. Sends an error to the
+// caller and returns false if it isn't a valid course code.
+func courseCodeParam(ctx *gin.Context) (string, bool) {
+	code := strings.ToUpper(ctx.Param("courseCode"))
+	if !isAlphanumeric(code) {
+		sendProblem(ctx, newInvalidParams(
+			fmt.Sprintf("Invalid course code %q", ctx.Param("courseCode")),
+			InvalidParam{Name: "courseCode", Reason: "is not a course code"},
+		))
+		return "", false
+	}
+	return code, true
+}
+
+// General method for getting a single resource, such as one course, and
+// sending the response to the caller. `fetch` retrieves the resource once args
+// are parsed, returning a NotFoundError if it doesn't exist; `byTerm` is true
+// if the resource can be filtered by term.
+func (server Server) getResourceAndSendResponse(ctx *gin.Context, path string, ttl time.Duration,
+	byTerm bool, fetch func(ResourceArgs) (any, error)) {
+	var args ResourceArgs
+	if err := ctx.ShouldBindQuery(&args); err != nil {
+		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, err)
+		return
+	}
+	if !byTerm && args.Term != "" {
+		sendProblem(ctx, newInvalidParams(
+			"Cannot specify term for instructors; use /v0/instructors/active",
+			InvalidParam{Name: "term", Reason: "is not supported for instructors"},
+		))
+		return
+	}
+
+	key := buildCacheKey(ctx.Request)
+	if server.serveFromCache(ctx, path, key) {
+		return
+	}
+	if byTerm && !server.resolveTerm(ctx, path, &args.Term) {
 		return
 	}
 
-	client.writeAndCacheResponse(ctx, res, path, key, ttl)
+	// Get data from DB
+	resource, err := fetch(args)
+	server.writeAndCacheResult(ctx, resource, err, path, key, ttl)
 }
 
 /* =============================== HANDLERS ================================ */
@@ -407,25 +893,44 @@ func handleDocs(ctx *gin.Context) {
 }
 
 // Base v0 endpoint
-func (client SupabaseClient) handleBaseEndpoint(ctx *gin.Context) {
+func (server Server) handleBaseEndpoint(ctx *gin.Context) {
 	ctx.String(http.StatusOK, "Welcome to the Jupiterp API!")
 }
 
+// Unknown paths. v1 sends a not-found problem; other paths are left to gin,
+// which sends a plain 404 as it always has.
+func handleNoRoute(ctx *gin.Context) {
+	if isV1(ctx) {
+		sendProblem(ctx, newProblem(problemNotFound, http.StatusNotFound,
+			fmt.Sprintf("No endpoint at %s %s; see / for the available endpoints", ctx.Request.Method, ctx.Request.URL.Path)))
+	}
+}
+
 // Get a list of courses WITHOUT any section info.
 // Example: /v0/courses/?limit=10&offset=50&prefix=CMSC
-func (client SupabaseClient) handleGetCourses(ctx *gin.Context) {
+func (server Server) handleGetCourses(ctx *gin.Context) {
 	path := "v0/courses"
-	client.getCoursesAndSendResponse(ctx, []string{"*"}, path, coursesTTL)
+	server.getCoursesAndSendResponse(ctx, path, coursesTTL, func(args CoursesArgs) (any, error) {
+		courses, err := server.source.Courses(args)
+		if args.ParsedConditions {
+			for i := range courses {
+				courses[i].ParsedConditions = parseConditions(courses[i].Conditions)
+			}
+		}
+		return courses, err
+	}, server.source.CountCourses)
 }
 
 // Get a minified list of courses. Returns only the course code and title.
 // Same arguments as `handleGetCourses`.
-func (client SupabaseClient) handleMinifiedCourses(ctx *gin.Context) {
+func (server Server) handleMinifiedCourses(ctx *gin.Context) {
 	path := "v0/courses/minified"
-	client.getCoursesAndSendResponse(ctx, []string{"course_code", "name"}, path, coursesTTL)
+	server.getCoursesAndSendResponse(ctx, path, coursesTTL, func(args CoursesArgs) (any, error) {
+		return server.source.MinifiedCourses(args)
+	}, server.source.CountCourses)
 }
 
-func (client SupabaseClient) handleCoursesWithSections(ctx *gin.Context) {
+func (server Server) handleCoursesWithSections(ctx *gin.Context) {
 	path := "v0/courses/withSections"
 
 	var args CoursesWithSectionsArgs
@@ -433,35 +938,75 @@ func (client SupabaseClient) handleCoursesWithSections(ctx *gin.Context) {
 		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, err)
 		return
 	}
-	if (args.CourseCodes != "" && args.Prefix != "" && args.Number != "") ||
-		(args.CourseCodes != "" && args.Prefix != "") ||
-		(args.CourseCodes != "" && args.Number != "") ||
-		(args.Prefix != "" && args.Number != "") {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": "Cannot specify courseCodes, prefix, and number simultaneously",
-		})
+	if problem, ok := checkCourseFilters(args.CourseCodes, args.Prefix, args.Number); !ok {
+		problem.legacyError = "Cannot specify courseCodes, prefix, and number simultaneously"
+		sendProblem(ctx, problem)
+		return
+	}
+	constraints, err := args.MeetingFilter.parse()
+	if err != nil {
+		sendProblem(ctx, newInvalidParamError(fmt.Sprintf("Invalid meeting filter: %s", err), "", err))
+		return
+	}
+	ratings, ok := parseSectionRatings(ctx, &args.SortBy, args.MinInstructorRating)
+	if !ok {
+		return
+	}
+	if problem, ok := checkFilterScope(args.MeetingFilter, ratings, args.CourseCodes, args.Prefix); !ok {
+		sendProblem(ctx, problem)
 		return
 	}
 
 	args.setDefaults()
+	if !args.resolveCursor(ctx, &args.Offset) {
+		return
+	}
 
 	key := buildCacheKey(ctx.Request)
-	if client.serveFromCache(ctx, path, key) {
+	if server.serveFromCache(ctx, path, key) {
+		return
+	}
+	if !server.resolveTerm(ctx, path, &args.Term) {
 		return
 	}
 
-	// Get data from DB
-	res, err := client.getCoursesWithSections(args)
-	if err != nil {
-		sendInternalError(ctx, path, err)
+	matched, err := server.resolveInstructor(&args.Instructor, &args.AnyInstructor, args.InstructorMatch, args.Term)
+	if err != nil || !matched {
+		total, _ := args.countTotal(func() (int, error) { return 0, nil })
+		server.writeAndCachePage(ctx, []CourseWithSections{}, page{offset: args.Offset, limit: args.Limit, total: total},
+			err, path, key, sectionsTTL)
 		return
 	}
 
-	client.writeAndCacheResponse(ctx, res, path, key, sectionsTTL)
+	if ratings.isConfigured() || args.Expand == expandInstructors {
+		if ratings.linker, err = server.getInstructorLinker(); err != nil {
+			sendInternalError(ctx, path, err)
+			return
+		}
+	}
+
+	// Get data from DB
+	courses, knownTotal, err := fetchCoursesWithSections(server.source, args, constraints, ratings)
+	if err == nil && args.Expand == expandInstructors {
+		for i := range courses {
+			ratings.linker.linkSections(courses[i].Sections)
+		}
+	}
+	total := -1
+	if err == nil {
+		total, err = args.countTotal(func() (int, error) {
+			if knownTotal >= 0 {
+				return knownTotal, nil
+			}
+			return countCoursesWithSections(server.source, args, constraints, ratings)
+		})
+	}
+	server.writeAndCachePage(ctx, courses, page{offset: args.Offset, limit: args.Limit, total: total},
+		err, path, key, sectionsTTL)
 }
 
 // Get a list of sections for a given course.
-func (client SupabaseClient) handleGetSections(ctx *gin.Context) {
+func (server Server) handleGetSections(ctx *gin.Context) {
 	path := "v0/sections"
 
 	var args SectionsArgs
@@ -469,50 +1014,325 @@ func (client SupabaseClient) handleGetSections(ctx *gin.Context) {
 		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, err)
 		return
 	}
+	constraints, err := args.MeetingFilter.parse()
+	if err != nil {
+		sendProblem(ctx, newInvalidParamError(fmt.Sprintf("Invalid meeting filter: %s", err), "", err))
+		return
+	}
+	ratings, ok := parseSectionRatings(ctx, &args.SortBy, args.MinInstructorRating)
+	if !ok {
+		return
+	}
+	if problem, ok := checkFilterScope(args.MeetingFilter, ratings, args.CourseCodes, args.CoursePrefix); !ok {
+		sendProblem(ctx, problem)
+		return
+	}
 	args.setDefaults()
+	if !args.resolveCursor(ctx, &args.Offset) {
+		return
+	}
 
 	key := buildCacheKey(ctx.Request)
-	if client.serveFromCache(ctx, path, key) {
+	if server.serveFromCache(ctx, path, key) {
+		return
+	}
+	if !server.resolveTerm(ctx, path, &args.Term) {
 		return
 	}
 
-	// Get data from DB
-	res, err := client.getSections(args)
-	if err != nil {
-		sendInternalError(ctx, path, err)
+	matched, err := server.resolveInstructor(&args.Instructor, &args.AnyInstructor, args.InstructorMatch, args.Term)
+	if err != nil || !matched {
+		total, _ := args.countTotal(func() (int, error) { return 0, nil })
+		server.writeAndCachePage(ctx, []Section{}, page{offset: args.Offset, limit: args.Limit, total: total},
+			err, path, key, sectionsTTL)
 		return
 	}
 
-	client.writeAndCacheResponse(ctx, res, path, key, sectionsTTL)
+	if ratings.isConfigured() || args.Expand == expandInstructors {
+		if ratings.linker, err = server.getInstructorLinker(); err != nil {
+			sendInternalError(ctx, path, err)
+			return
+		}
+	}
+
+	// Get data from DB
+	sections, knownTotal, err := fetchSections(server.source, args, constraints, ratings)
+	if err == nil && args.Expand == expandInstructors {
+		ratings.linker.linkSections(sections)
+	}
+	total := -1
+	if err == nil {
+		total, err = args.countTotal(func() (int, error) {
+			if knownTotal >= 0 {
+				return knownTotal, nil
+			}
+			return countSections(server.source, args, constraints, ratings)
+		})
+	}
+	server.writeAndCachePage(ctx, sections, page{offset: args.Offset, limit: args.Limit, total: total},
+		err, path, key, sectionsTTL)
 }
 
 // Get a list of instructors with their ratings.
-func (client SupabaseClient) handleGetInstructors(ctx *gin.Context) {
+func (server Server) handleGetInstructors(ctx *gin.Context) {
 	path := "v0/instructors"
-	client.getInstructorsAndSendResponse(ctx, path, "instructors", instructorsTTL)
+	server.getInstructorsAndSendResponse(ctx, path, instructorsTTL, false,
+		server.source.Instructors, server.source.CountInstructors)
 }
 
 // Get a list of instructors currently teaching courses.
-func (client SupabaseClient) handleGetActiveInstructors(ctx *gin.Context) {
+func (server Server) handleGetActiveInstructors(ctx *gin.Context) {
 	path := "v0/instructors/active"
-	client.getInstructorsAndSendResponse(ctx, path, "active_instructors", instructorsTTL)
+	server.getInstructorsAndSendResponse(ctx, path, instructorsTTL, true,
+		server.source.ActiveInstructors, server.source.CountActiveInstructors)
 }
 
-// Get a list of all 4-letter department codes.
-func (client SupabaseClient) handleGetDepartments(ctx *gin.Context) {
-	path := "v0/deptList"
+// Get a single instructor and their rating.
+// Example: /v0/instructors/abadi_daniel
+func (server Server) handleGetInstructor(ctx *gin.Context) {
+	path := "v0/instructors/:slug"
+	slug := ctx.Param("slug")
+	if !isSlug(slug) {
+		sendProblem(ctx, newInvalidParams(
+			fmt.Sprintf("Invalid instructor slug %q", slug),
+			InvalidParam{Name: "slug", Reason: "is not an instructor slug"},
+		))
+		return
+	}
+	server.getResourceAndSendResponse(ctx, path, instructorsTTL, false, func(args ResourceArgs) (any, error) {
+		instructors, err := server.source.Instructors(InstructorArgs{InstructorSlugs: slug, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(instructors) == 0 {
+			return nil, &NotFoundError{Message: fmt.Sprintf("Instructor %s not found", slug)}
+		}
+		return instructors[0], nil
+	})
+}
+
+// Get a single instructor and the sections they teach.
+// Example: /v0/instructors/abadi_daniel/sections?term=202601
+func (server Server) handleGetInstructorSections(ctx *gin.Context) {
+	path := "v0/instructors/:slug/sections"
+	slug := ctx.Param("slug")
+	if !isSlug(slug) {
+		sendProblem(ctx, newInvalidParams(
+			fmt.Sprintf("Invalid instructor slug %q", slug),
+			InvalidParam{Name: "slug", Reason: "is not an instructor slug"},
+		))
+		return
+	}
+	server.getResourceAndSendResponse(ctx, path, sectionsTTL, true, func(args ResourceArgs) (any, error) {
+		instructors, err := server.source.Instructors(InstructorArgs{InstructorSlugs: slug, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(instructors) == 0 {
+			return nil, &NotFoundError{Message: fmt.Sprintf("Instructor %s not found", slug)}
+		}
+		joined, err := withSections(server.source, instructors, args.Term)
+		if err != nil {
+			return nil, err
+		}
+		return joined[0], nil
+	})
+}
+
+// Check whether `s` could be an instructor slug. Slugs are mostly lower-case
+// letters and underscores, but may contain other characters from names, like
+// apostrophes; only characters that can't appear in a slug are rejected.
+func isSlug(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`,()"\`, r)
+	})
+}
+
+// Check a schedule of sections for time conflicts, missing or full sections,
+// and total credits.
+func (server Server) handleCheckSchedule(ctx *gin.Context) {
+	path := "v0/schedules/check"
+
+	var req ScheduleCheckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Printf("Received POST %s with malformed body: %v", path, err)
+		sendProblem(ctx, newProblem(problemInvalidBody, http.StatusBadRequest,
+			"Malformed request body. Expected JSON with a list of sections."))
+		return
+	}
+	if err := req.validate(); err != nil {
+		sendProblem(ctx, newProblem(problemInvalidBody, http.StatusBadRequest, fmt.Sprintf("Invalid schedule: %s", err)))
+		return
+	}
+	if !server.resolveTerm(ctx, path, &req.Term) {
+		return
+	}
+
+	// Results depend on current seat counts, so they aren't cached
+	result, err := checkSchedule(server.source, req)
+	server.writeAndCacheResult(ctx, result, err, path, "", 0)
+}
+
+// Get a single course WITHOUT any section info.
+// Example: /v0/courses/CMSC131
+func (server Server) handleGetCourse(ctx *gin.Context) {
+	path := "v0/courses/:courseCode"
+	code, ok := courseCodeParam(ctx)
+	if !ok {
+		return
+	}
+	server.getResourceAndSendResponse(ctx, path, coursesTTL, true, func(args ResourceArgs) (any, error) {
+		courses, err := server.source.Courses(CoursesArgs{CourseCodes: code, Term: args.Term, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(courses) == 0 {
+			return nil, &NotFoundError{Message: fmt.Sprintf("Course %s not found", code)}
+		}
+		if args.ParsedConditions {
+			courses[0].ParsedConditions = parseConditions(courses[0].Conditions)
+		}
+		return courses[0], nil
+	})
+}
+
+// Get a single section of a course.
+// Example: /v0/courses/CMSC131/sections/0101
+func (server Server) handleGetSection(ctx *gin.Context) {
+	path := "v0/courses/:courseCode/sections/:secCode"
+	code, ok := courseCodeParam(ctx)
+	if !ok {
+		return
+	}
+	secCode := strings.ToUpper(ctx.Param("secCode"))
+	if !isAlphanumeric(secCode) {
+		sendProblem(ctx, newInvalidParams(
+			fmt.Sprintf("Invalid section code %q", ctx.Param("secCode")),
+			InvalidParam{Name: "secCode", Reason: "is not a section code"},
+		))
+		return
+	}
+	server.getResourceAndSendResponse(ctx, path, sectionsTTL, true, func(args ResourceArgs) (any, error) {
+		ref := SectionRef{CourseCode: code, SecCode: secCode}
+		sections, err := fetchSectionsByRef(server.source, []SectionRef{ref}, []string{code}, args.Term)
+		if err != nil {
+			return nil, err
+		}
+		if len(sections) == 0 {
+			return nil, &NotFoundError{Message: fmt.Sprintf("Section %s of %s not found", secCode, code)}
+		}
+		return sections[0], nil
+	})
+}
+
+// Search courses by code, name, and description, returning the best matches
+// first.
+// Example: /v0/courses/search?q=machine%20learning
+func (server Server) handleSearchCourses(ctx *gin.Context) {
+	path := "v0/courses/search"
+
+	var args SearchArgs
+	if err := ctx.ShouldBindQuery(&args); err != nil {
+		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, err)
+		return
+	}
+	args.setDefaults()
 
 	key := buildCacheKey(ctx.Request)
-	if client.serveFromCache(ctx, path, key) {
+	if server.serveFromCache(ctx, path, key) {
+		return
+	}
+	if !server.resolveTerm(ctx, path, &args.Term) {
 		return
 	}
 
-	// Get data from DB
-	res, err := client.getDepartments()
+	index, err := server.getSearchIndex(args.Term)
+	var page []SearchResult
+	if err == nil {
+		page = paginate(index.search(args.Query), args.Offset, args.Limit)
+	}
+	server.writeAndCacheResult(ctx, page, err, path, key, coursesTTL)
+}
+
+// Get the prerequisites of a course, with the prerequisites of each of those
+// courses expanded up to the given depth.
+// Example: /v0/courses/CMSC330/prerequisites?depth=2
+func (server Server) handleGetPrerequisites(ctx *gin.Context) {
+	path := "v0/courses/:courseCode/prerequisites"
+	server.traversePrerequisitesAndSendResponse(ctx, path,
+		func(graph prerequisiteGraph, code string, args PrerequisiteArgs) any {
+			return graph.prerequisiteTree(code, int(args.Depth))
+		})
+}
+
+// Get the courses that list a course as a prerequisite.
+// Example: /v0/courses/CMSC216/unlocks
+func (server Server) handleGetUnlocks(ctx *gin.Context) {
+	path := "v0/courses/:courseCode/unlocks"
+	server.traversePrerequisitesAndSendResponse(ctx, path,
+		func(graph prerequisiteGraph, code string, args PrerequisiteArgs) any {
+			return graph.unlocks(code)
+		})
+}
+
+// Generate schedules with one section of each of the given courses and no
+// conflicting meetings.
+func (server Server) handleGenerateSchedules(ctx *gin.Context) {
+	path := "v0/schedules/generate"
+
+	var args GenerateSchedulesArgs
+	if err := ctx.ShouldBindQuery(&args); err != nil {
+		sendInvalidArgsError(ctx, reflect.TypeOf(args), path, err)
+		return
+	}
+	codes, err := args.courseCodes()
 	if err != nil {
-		sendInternalError(ctx, path, err)
+		sendProblem(ctx, newInvalidParamError(fmt.Sprintf("Invalid courseCodes: %s", err), "courseCodes", err))
 		return
 	}
+	constraints, err := args.MeetingFilter.parse()
+	if err != nil {
+		sendProblem(ctx, newInvalidParamError(fmt.Sprintf("Invalid meeting filter: %s", err), "", err))
+		return
+	}
+	args.setDefaults()
 
-	client.writeAndCacheResponse(ctx, res, path, key, departmentsTTL)
+	key := buildCacheKey(ctx.Request)
+	if server.serveFromCache(ctx, path, key) {
+		return
+	}
+	if !server.resolveTerm(ctx, path, &args.Term) {
+		return
+	}
+
+	// Get data from DB
+	schedules, err := generateSchedules(server.source, args, codes, constraints)
+	server.writeAndCacheResult(ctx, schedules, err, path, key, sectionsTTL)
+}
+
+// Get a list of available terms, marking the default term.
+func (server Server) handleGetTerms(ctx *gin.Context) {
+	path := "v0/terms"
+
+	key := buildCacheKey(ctx.Request)
+	if server.serveFromCache(ctx, path, key) {
+		return
+	}
+
+	terms, err := server.getTerms()
+	server.writeAndCacheResult(ctx, terms, err, path, key, termsTTL)
+}
+
+// Get a list of all 4-letter department codes.
+func (server Server) handleGetDepartments(ctx *gin.Context) {
+	path := "v0/deptList"
+
+	key := buildCacheKey(ctx.Request)
+	if server.serveFromCache(ctx, path, key) {
+		return
+	}
+
+	// Get data from DB
+	departments, err := server.source.Departments()
+	server.writeAndCacheResult(ctx, departments, err, path, key, departmentsTTL)
 }
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Create a server for the fixtures in the fixtures directory.
+func newTestServer(t *testing.T) Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if err := registerArgValidators(); err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := NewFixtureSource("fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newServer(fixtures, NewLRUCache(defaultCacheCapacity))
+}
+
+// Send a request to `server` and get the response.
+func serve(server Server, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCacheHeaders(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		status       int
+		cacheControl string
+		validators   bool
+	}{
+		{
+			name:         "schedule check",
+			method:       http.MethodPost,
+			target:       "/v0/schedules/check",
+			body:         `{"sections": [{"course_code": "CMSC131", "sec_code": "0101"}]}`,
+			status:       http.StatusOK,
+			cacheControl: "no-store",
+		},
+		{
+			name:         "v1 schedule check",
+			method:       http.MethodPost,
+			target:       "/v1/schedules/check",
+			body:         `{"sections": [{"course_code": "CMSC131", "sec_code": "0101"}]}`,
+			status:       http.StatusOK,
+			cacheControl: "no-store",
+		},
+		{
+			name:         "invalid schedule check",
+			method:       http.MethodPost,
+			target:       "/v1/schedules/check",
+			body:         `{"sections": []}`,
+			status:       http.StatusBadRequest,
+			cacheControl: "no-store",
+		},
+		{
+			name:         "course",
+			method:       http.MethodGet,
+			target:       "/v0/courses/CMSC131",
+			status:       http.StatusOK,
+			cacheControl: "public, max-age=7200, stale-while-revalidate=7200, stale-if-error=86400",
+			validators:   true,
+		},
+		{
+			name:         "missing course",
+			method:       http.MethodGet,
+			target:       "/v1/courses/CMSC999",
+			status:       http.StatusNotFound,
+			cacheControl: "no-store",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(server, test.method, test.target, test.body)
+			if w.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.status, w.Body)
+			}
+			if got := w.Header().Get("Cache-Control"); got != test.cacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, test.cacheControl)
+			}
+			for _, name := range []string{"ETag", "Last-Modified"} {
+				if got := w.Header().Get(name); (got != "") != test.validators {
+					t.Errorf("%s = %q, want it set: %t", name, got, test.validators)
+				}
+			}
+		})
+	}
+}
+
+func TestTerms(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{target: "/v0/terms", status: http.StatusOK,
+			body: `[{"term":"202508","name":"Fall 2025","is_default":true},{"term":"202601","name":"Spring 2026","is_default":false}]`},
+		{target: "/v0/courses?courseCodes=CMSC131&term=202601", status: http.StatusOK},
+		{target: "/v0/courses?courseCodes=CMSC131&term=209901", status: http.StatusBadRequest},
+		{target: "/v0/terms", status: http.StatusOK,
+			body: `[{"term":"202508","name":"Fall 2025","is_default":true},{"term":"202601","name":"Spring 2026","is_default":false}]`},
+	}
+	for _, test := range tests {
+		w := serve(server, http.MethodGet, test.target, "")
+		if w.Code != test.status {
+			t.Errorf("GET %s: status = %d, want %d: %s", test.target, w.Code, test.status, w.Body)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("GET %s = %s, want %s", test.target, w.Body, test.body)
+		}
+	}
+}
+
+func TestFilterScope(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		target string
+		status int
+		params string
+	}{
+		{target: "/v1/courses/withSections?minInstructorRating=4", status: http.StatusBadRequest,
+			params: `[{"name":"minInstructorRating","reason":"requires courseCodes or a prefix of at least a department code"}]`},
+		{target: "/v1/sections?days=MWF&startAfter=10:00&count=exact", status: http.StatusBadRequest,
+			params: `[{"name":"days","reason":"requires courseCodes or a prefix of at least a department code"},` +
+				`{"name":"startAfter","reason":"requires courseCodes or a prefix of at least a department code"}]`},
+		{target: "/v1/sections?prefix=CMS&sortBy=instructor_rating.desc", status: http.StatusBadRequest,
+			params: `[{"name":"sortBy","reason":"instructor_rating requires courseCodes or a prefix of at least a department code"}]`},
+		{target: "/v1/sections?number=131&excludeDays=F", status: http.StatusBadRequest,
+			params: `[{"name":"excludeDays","reason":"requires courseCodes or a prefix of at least a department code"}]`},
+		{target: "/v1/sections?prefix=CMSC&days=MWF&count=exact", status: http.StatusOK},
+		{target: "/v1/sections?courseCodes=CMSC131&sortBy=instructor_rating.desc", status: http.StatusOK},
+		{target: "/v1/courses/withSections?prefix=CMSC1&minInstructorRating=1", status: http.StatusOK},
+		{target: "/v1/sections?count=exact", status: http.StatusOK},
+	}
+	for _, test := range tests {
+		w := serve(server, http.MethodGet, test.target, "")
+		if w.Code != test.status {
+			t.Errorf("GET %s: status = %d, want %d: %s", test.target, w.Code, test.status, w.Body)
+			continue
+		}
+		if test.params == "" {
+			continue
+		}
+		var problem struct{ InvalidParams json.RawMessage }
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatal(err)
+		}
+		if string(problem.InvalidParams) != test.params {
+			t.Errorf("GET %s: invalidParams = %s, want %s", test.target, problem.InvalidParams, test.params)
+		}
+	}
+}
+
+func TestProblems(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{
+			name:        "both instructorNames and instructorSlugs",
+			method:      http.MethodGet,
+			target:      "/v1/instructors?instructorNames=a&instructorSlugs=b",
+			status:      http.StatusBadRequest,
+			contentType: problemContentType,
+			body: `{"type":"https://api.jupiterp.com/problems/invalid-parameters","title":"Invalid parameters","status":400,` +
+				`"detail":"Cannot specify both instructorNames and instructorSlugs",` +
+				`"instance":"/v1/instructors?instructorNames=a&instructorSlugs=b",` +
+				`"invalidParams":[{"name":"instructorNames","reason":"cannot be used with instructorSlugs"},` +
+				`{"name":"instructorSlugs","reason":"cannot be used with instructorNames"}],"requestId":"test"}`,
+		},
+		{
+			name:        "v0 both instructorNames and instructorSlugs",
+			method:      http.MethodGet,
+			target:      "/v0/instructors?instructorNames=a&instructorSlugs=b",
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"error":"Malformed query parameters. Check types and ranges."}`,
+		},
+		{
+			name:        "unknown v1 path",
+			method:      http.MethodGet,
+			target:      "/v1/nope",
+			status:      http.StatusNotFound,
+			contentType: problemContentType,
+			body: `{"type":"https://api.jupiterp.com/problems/not-found","title":"Not found","status":404,` +
+				`"detail":"No endpoint at GET /v1/nope; see / for the available endpoints","instance":"/v1/nope","requestId":"test"}`,
+		},
+		{
+			name:        "unknown v1 method",
+			method:      http.MethodPost,
+			target:      "/v1/courses",
+			status:      http.StatusNotFound,
+			contentType: problemContentType,
+			body: `{"type":"https://api.jupiterp.com/problems/not-found","title":"Not found","status":404,` +
+				`"detail":"No endpoint at POST /v1/courses; see / for the available endpoints","instance":"/v1/courses","requestId":"test"}`,
+		},
+		{
+			name:        "unknown v0 path",
+			method:      http.MethodGet,
+			target:      "/v0/nope",
+			status:      http.StatusNotFound,
+			contentType: "text/plain",
+			body:        "404 page not found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, nil)
+			req.Header.Set(requestIDHeader, "test")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+			if w.Code != test.status {
+				t.Errorf("status = %d, want %d", w.Code, test.status)
+			}
+			if got := w.Header().Get("Content-Type"); got != test.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, test.contentType)
+			}
+			if w.Body.String() != test.body {
+				t.Errorf("body = %s, want %s", w.Body, test.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// An instructor along with the sections they teach.
+type InstructorWithSections struct {
+	Instructor
+	Sections []Section `json:"sections"`
+}
+
+// Maximum number of instructor names in one query for their sections or
+// ratings, so the filter doesn't make the URL too long for the database or a
+// proxy.
+const instructorNamesPerQuery = 50
+
+// Get the sections in `term` taught by each of the instructors named in
+// `names`, by name. Sections are ordered by course and section code.
+func fetchSectionsByInstructor(source DataSource, names []string, term string) (map[string][]Section, error) {
+	byName := make(map[string][]Section, len(names))
+	names = slices.Compact(slices.Sorted(slices.Values(names)))
+	for batch := range slices.Chunk(names, instructorNamesPerQuery) {
+		args := SectionsArgs{
+			AnyInstructor: batch,
+			Term:          term,
+			SortBy:        "course_code.asc,sec_code.asc",
+			Limit:         filteredPageSize,
+		}
+		for offset := 0; ; offset += filteredPageSize {
+			args.Offset = offset
+			sections, err := source.Sections(args)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range sections {
+				for _, name := range s.Instructors {
+					if slices.Contains(batch, name) {
+						byName[name] = append(byName[name], s)
+					}
+				}
+			}
+			if len(sections) < filteredPageSize {
+				break
+			}
+		}
+	}
+	return byName, nil
+}
+
+// Join each instructor to the sections they teach in `term`.
+func withSections(source DataSource, instructors []Instructor, term string) ([]InstructorWithSections, error) {
+	names := make([]string, len(instructors))
+	for i, instructor := range instructors {
+		names[i] = instructor.Name
+	}
+	byName, err := fetchSectionsByInstructor(source, names, term)
+	if err != nil {
+		return nil, err
+	}
+	joined := make([]InstructorWithSections, len(instructors))
+	for i, instructor := range instructors {
+		sections := byName[instructor.Name]
+		if sections == nil {
+			sections = []Section{}
+		}
+		joined[i] = InstructorWithSections{Instructor: instructor, Sections: sections}
+	}
+	return joined, nil
+}
+
+/* ================================ MATCHING =============================== */
+
+// Modes for matching instructor names given as query args.
+const (
+	// Names must match exactly, including case.
+	instructorMatchExact = "exact"
+
+	// Names must match ignoring case, diacritics, and punctuation; a single
+	// word matches instructors with that last name.
+	instructorMatchCI = "ci"
+
+	// Each word of the query must match a word of the name, ignoring case,
+	// diacritics, and punctuation, with a typo allowed in longer words. For
+	// example, "pines" matches "Darryll Pines".
+	instructorMatchFuzzy = "fuzzy"
+)
+
+// Get all instructors, from the cache if possible. These are used to resolve
+// names given with non-exact matching and to link section instructors to
+// their ratings.
+func (server Server) getAllInstructors() ([]Instructor, error) {
+	return server.instructors.get("", func() ([]Instructor, error) {
+		all := []Instructor{}
+		args := InstructorArgs{Limit: filteredPageSize}
+		for offset := 0; ; offset += filteredPageSize {
+			args.Offset = offset
+			instructors, err := server.source.Instructors(args)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, instructors...)
+			if len(instructors) < filteredPageSize {
+				return all, nil
+			}
+		}
+	})
+}
+
+// Get the distinct names of the instructors teaching sections in `term`, or in
+// any term if `term` is empty, from the cache if possible. These are used to
+// resolve names given with non-exact matching, and include instructors without
+// a PlanetTerp profile, unlike getAllInstructors.
+func (server Server) getSectionInstructorNames(term string) ([]string, error) {
+	return server.sectionInstructors.get(term, func() ([]string, error) {
+		seen := map[string]bool{}
+		names := []string{}
+		args := SectionsArgs{Term: term, Limit: filteredPageSize}
+		for offset := 0; ; offset += filteredPageSize {
+			args.Offset = offset
+			sections, err := server.source.SectionInstructors(args)
+			if err != nil {
+				return nil, err
+			}
+			for _, instructors := range sections {
+				for _, name := range instructors {
+					if !seen[name] && !isPlaceholderInstructor(name) {
+						seen[name] = true
+						names = append(names, name)
+					}
+				}
+			}
+			if len(sections) < filteredPageSize {
+				return names, nil
+			}
+		}
+	})
+}
+
+// Get the names in `names` that match `query` under `mode`.
+func matchInstructorNames(query string, names []string, mode string) []string {
+	queryWords := nameWords(query)
+	matches := []string{}
+	if len(queryWords) == 0 {
+		return matches
+	}
+	for _, name := range names {
+		words := nameWords(name)
+		var ok bool
+		switch mode {
+		case instructorMatchCI:
+			ok = slices.Equal(queryWords, words) ||
+				len(queryWords) == 1 && len(words) > 0 && queryWords[0] == words[len(words)-1]
+		case instructorMatchFuzzy:
+			ok = !slices.ContainsFunc(queryWords, func(q string) bool {
+				return !slices.ContainsFunc(words, func(w string) bool {
+					return q == w || len(q) >= 5 && editDistance(q, w, 1) <= 1
+				})
+			})
+		default:
+			ok = name == query
+		}
+		if ok {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
+
+// Split a name into normalized words for matching: lower case, without
+// diacritics, and without apostrophes (straight or curly) or other
+// punctuation. For example, "Terrence O’Brien" becomes [terrence obrien].
+func nameWords(name string) []string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop combining marks left by decomposing accented letters
+		case r == '\'' || r == '‘' || r == '’' || r == '`':
+			// Drop apostrophes so O'Brien, O’Brien, and OBrien match
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+/* ================================ LINKING ================================ */
+
+// Value of the expand query arg that links section instructors to their
+// PlanetTerp slugs and ratings.
+const expandInstructors = "instructors"
+
+// Instructors indexed by name, for linking the instructor names of sections,
+// which come from Testudo, to instructors on PlanetTerp.
+type instructorLinker struct {
+	byName map[string]*Instructor
+
+	// Instructors by normalized name (see nameWords); nil if more than one
+	// instructor has the same normalized name, so the link would be a guess.
+	byWords map[string]*Instructor
+}
+
+func newInstructorLinker(instructors []Instructor) *instructorLinker {
+	linker := &instructorLinker{
+		byName:  make(map[string]*Instructor, len(instructors)),
+		byWords: make(map[string]*Instructor, len(instructors)),
+	}
+	for i := range instructors {
+		instructor := &instructors[i]
+		linker.byName[instructor.Name] = instructor
+		words := strings.Join(nameWords(instructor.Name), " ")
+		if _, ok := linker.byWords[words]; ok {
+			linker.byWords[words] = nil
+		} else {
+			linker.byWords[words] = instructor
+		}
+	}
+	return linker
+}
+
+// Get the instructor named `name`, matching exactly if possible and ignoring
+// case, diacritics, and punctuation otherwise. Returns nil for placeholders
+// like "Instructor: TBA" and for names that can't be linked to one instructor.
+func (linker *instructorLinker) link(name string) *Instructor {
+	if isPlaceholderInstructor(name) {
+		return nil
+	}
+	if instructor, ok := linker.byName[name]; ok {
+		return instructor
+	}
+	return linker.byWords[strings.Join(nameWords(name), " ")]
+}
+
+// Set the linked instructors of each section, in the same order as its
+// instructor names.
+func (linker *instructorLinker) linkSections(sections []Section) {
+	for i := range sections {
+		linked := make([]*Instructor, len(sections[i].Instructors))
+		for j, name := range sections[i].Instructors {
+			linked[j] = linker.link(name)
+		}
+		sections[i].LinkedInstructors = linked
+	}
+}
+
+// Check whether `name` is a placeholder for an instructor who hasn't been
+// assigned yet, such as "Instructor: TBA", rather than an actual name.
+func isPlaceholderInstructor(name string) bool {
+	name = strings.TrimSpace(name)
+	return name == "" || strings.HasPrefix(strings.ToLower(name), "instructor:") || strings.EqualFold(name, "TBA")
+}
+
+// Get an instructorLinker over all instructors.
+func (server Server) getInstructorLinker() (*instructorLinker, error) {
+	instructors, err := server.getAllInstructors()
+	if err != nil {
+		return nil, err
+	}
+	return newInstructorLinker(instructors), nil
+}
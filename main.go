@@ -6,17 +6,39 @@ retrieved from PlanetTerp (these can be accessed directly via the PlanetTerp
 API).
 
 This binary uses the following environment variables:
-  - DATABASE_URL (mandatory): The database URL to retrieve course, section,
-    and instructor data from
-  - DATABASE_KEY (mandatory): The database key used to access course, section,
-    and instructor data
+  - DATABASE_URL (mandatory unless FIXTURES_DIR is set): The database URL to
+    retrieve course, section, and instructor data from
+  - DATABASE_KEY (mandatory unless FIXTURES_DIR is set): The database key used
+    to access course, section, and instructor data
+  - FIXTURES_DIR (optional): A directory of local JSON/NDJSON fixture files to
+    serve data from instead of the database; see FixtureSource
   - PORT (optional): The port to serve API on; default is 8080
+  - CACHE_MAX_ENTRIES (optional): The maximum number of responses to cache;
+    default is 124, or no limit if CACHE_MAX_BYTES is set. If neither limit
+    is set (CACHE_MAX_ENTRIES=0), nothing is cached
+  - CACHE_MAX_BYTES (optional): The maximum total size of cached responses,
+    in bytes or with a unit such as 64MiB; default is no limit
+  - CACHE_MAX_ENTRY_BYTES (optional): The maximum size of a single cached
+    response; default is 1/8 of CACHE_MAX_BYTES if that is set
+  - CACHE_DIR (optional): A directory to also cache responses in, so they
+    survive restarts; default is to only cache in memory. Has no effect if
+    nothing is cached in memory
+  - CACHE_DIR_MAX_BYTES (optional): The maximum total size of responses cached
+    in CACHE_DIR; default is 256MiB
+  - METRICS_ADDR (optional): An address such as localhost:9090 to serve
+    metrics on at /debug/vars, separately from the API; default is to not
+    serve metrics. It shouldn't be reachable from outside, since the metrics
+    include the command line and memory stats
 */
 package main
 
 import (
+	"expvar"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -31,11 +53,55 @@ func mustEnv(key string) string {
 	return val
 }
 
+// Get the non-negative integer in env var `key`, or `def` if it is not
+// present. Fatal if it isn't a valid integer.
+func intEnv(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 {
+		log.Fatalf("invalid number in env var %s: %q", key, val)
+	}
+	return n
+}
+
+// Get the byte size in env var `key`, such as 1024, 512KiB, 64MiB, or 1GiB,
+// or `def` if it is not present. Fatal if it isn't a valid size.
+func byteSizeEnv(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	number, multiplier := val, 1
+	for suffix, m := range map[string]int{"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30} {
+		if n, ok := strings.CutSuffix(val, suffix); ok {
+			number, multiplier = n, m
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(number))
+	if err != nil || n < 0 {
+		log.Fatalf("invalid size in env var %s: %q", key, val)
+	}
+	return n * multiplier
+}
+
+// Get the limits of the response cache from env vars.
+func cacheLimitsFromEnv() CacheLimits {
+	limits := CacheLimits{MaxBytes: byteSizeEnv("CACHE_MAX_BYTES", 0)}
+	defaultEntries := defaultCacheCapacity
+	if limits.MaxBytes > 0 {
+		defaultEntries = 0
+	}
+	limits.MaxEntries = intEnv("CACHE_MAX_ENTRIES", defaultEntries)
+	limits.MaxEntryBytes = byteSizeEnv("CACHE_MAX_ENTRY_BYTES", limits.MaxBytes/8)
+	return limits
+}
+
 func main() {
 	log.SetFlags(log.Ldate | log.Ltime | log.LUTC | log.Lshortfile)
 
-	dbUrl := mustEnv("DATABASE_URL")
-	dbKey := mustEnv("DATABASE_KEY")
 	port := os.Getenv("PORT")
 
 	if port == "" {
@@ -43,17 +109,84 @@ func main() {
 		log.Printf("Defaulting to port %s", port)
 	}
 
+	if err := registerArgValidators(); err != nil {
+		log.Fatalf("failed to register query arg validators: %s", err)
+	}
+
+	// Serve from local fixtures if provided; otherwise, create SupabaseClient
+	// to connect with DB
+	var source DataSource
+	if fixturesDir := os.Getenv("FIXTURES_DIR"); fixturesDir != "" {
+		fixtures, err := NewFixtureSource(fixturesDir)
+		if err != nil {
+			log.Fatalf("failed to load fixtures from %s: %s", fixturesDir, err)
+		}
+		source = fixtures
+	} else {
+		source = SupabaseClient{
+			Url: mustEnv("DATABASE_URL"),
+			Key: mustEnv("DATABASE_KEY"),
+		}
+	}
+	limits := cacheLimitsFromEnv()
+	log.Printf("Caching up to %d responses and %d bytes (0 is no limit)", limits.MaxEntries, limits.MaxBytes)
+	server := newServer(source, NewLRUCacheWithLimits(limits))
+	var disk *DiskCache
+	if dir := os.Getenv("CACHE_DIR"); dir != "" {
+		var err error
+		disk, err = NewDiskCache(dir, byteSizeEnv("CACHE_DIR_MAX_BYTES", defaultDiskCacheBytes))
+		if err != nil {
+			log.Fatalf("failed to open cache dir %s: %s", dir, err)
+		}
+		server.cache.UseDisk(disk)
+	}
+	cacheMetrics.Set("entries", expvar.Func(func() any {
+		entries, _ := server.cache.Usage()
+		return entries
+	}))
+	cacheMetrics.Set("bytes", expvar.Func(func() any {
+		_, bytes := server.cache.Usage()
+		return bytes
+	}))
+	cacheMetrics.Set("diskFiles", expvar.Func(func() any {
+		files, _ := disk.Usage()
+		return files
+	}))
+	cacheMetrics.Set("diskBytes", expvar.Func(func() any {
+		_, bytes := disk.Usage()
+		return bytes
+	}))
+
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		go serveMetrics(addr)
+	}
+
+	// Listen and serve on defined port
+	log.Printf("Listening on port %s", port)
+	server.router.Run(":" + port)
+}
+
+// Create a server that serves data from `source`, caching responses in
+// `cache`, along with its router.
+func newServer(source DataSource, cache *LRUCache) Server {
 	// Initialize Gin instance and middleware
 	r := gin.New()
 	r.Use(gin.Recovery())
+	r.Use(requestID())
+	r.Use(finishFlights)
 	r.Use(cors.Default()) // default CORS config allows all origins
 	// TODO: Add logger, auth with keys
 
-	// Create SupabaseClient to connect with DB
-	client := SupabaseClient{
-		Url:   dbUrl,
-		Key:   dbKey,
-		cache: NewLRUCache(defaultCacheCapacity),
+	server := Server{
+		source:             source,
+		cache:              cache,
+		flights:            newFlightGroup(),
+		router:             r,
+		terms:              newDerivedCache[[]Term](termsTTL),
+		searchIndexes:      newDerivedCache[*searchIndex](coursesTTL),
+		instructors:        newDerivedCache[[]Instructor](instructorsTTL),
+		sectionInstructors: newDerivedCache[[]string](sectionsTTL),
+		prerequisiteGraphs: newDerivedCache[prerequisiteGraph](coursesTTL),
 	}
 
 	/* ========================== STATIC CONTENT =========================== */
@@ -65,21 +198,46 @@ func main() {
 
 	r.GET("/", handleDocs) // API Docs
 
-	v0 := r.Group("/v0")
-	v0.GET("/", client.handleBaseEndpoint) // base v0 endpoint
+	// v1 has the same routes as v0, but wraps responses in an Envelope
+	server.addRoutes(r.Group("/v0"))
+	server.addRoutes(r.Group("/v1"))
+	r.NoRoute(handleNoRoute)
 
-	v0.GET("/courses", client.handleGetCourses)                       // full courses
-	v0.GET("/courses/minified", client.handleMinifiedCourses)         // minified courses
-	v0.GET("/courses/withSections", client.handleCoursesWithSections) // courses with sections
+	return server
+}
 
-	v0.GET("/deptList", client.handleGetDepartments) // list of all 4-letter department codes
+// Serve metrics, such as cacheMetrics, at /debug/vars on `addr`.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	log.Printf("Serving metrics on %s", addr)
+	log.Fatalf("failed to serve metrics: %s", http.ListenAndServe(addr, mux))
+}
 
-	v0.GET("/sections", client.handleGetSections) // sections for courses
+// Add the API routes to `group`.
+func (server Server) addRoutes(group *gin.RouterGroup) {
+	group.GET("/", server.handleBaseEndpoint) // base endpoint
 
-	v0.GET("/instructors", client.handleGetInstructors)              // all instructors with ratings
-	v0.GET("/instructors/active", client.handleGetActiveInstructors) // all instructors currently teaching
+	group.GET("/courses", server.handleGetCourses)                                 // full courses
+	group.GET("/courses/minified", server.handleMinifiedCourses)                   // minified courses
+	group.GET("/courses/withSections", server.handleCoursesWithSections)           // courses with sections
+	group.GET("/courses/search", server.handleSearchCourses)                       // full-text course search
+	group.GET("/courses/:courseCode", server.handleGetCourse)                      // a single course
+	group.GET("/courses/:courseCode/sections/:secCode", server.handleGetSection)   // a single section
+	group.GET("/courses/:courseCode/prerequisites", server.handleGetPrerequisites) // transitive prerequisites
+	group.GET("/courses/:courseCode/unlocks", server.handleGetUnlocks)             // courses requiring a course
 
-	// Listen and serve on defined port
-	log.Printf("Listening on port %s", port)
-	r.Run(":" + port)
+	group.GET("/deptList", server.handleGetDepartments) // list of all 4-letter department codes
+
+	group.GET("/terms", server.handleGetTerms) // list of available terms
+
+	group.GET("/sections", server.handleGetSections) // sections for courses
+
+	group.POST("/schedules/check", server.handleCheckSchedule)       // check a schedule for conflicts
+	group.GET("/schedules/generate", server.handleGenerateSchedules) // generate non-conflicting schedules
+
+	group.GET("/instructors", server.handleGetInstructors)                       // all instructors with ratings
+	group.GET("/instructors/active", server.handleGetActiveInstructors)          // all instructors currently teaching
+	group.GET("/instructors/:slug", server.handleGetInstructor)                  // a single instructor
+	group.GET("/instructors/:slug/sections", server.handleGetInstructorSections) // an instructor and their sections
 }
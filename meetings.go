@@ -0,0 +1,461 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Possible modalities of a Meeting.
+const (
+	ModalityInPerson    = "InPerson"
+	ModalityOnlineSync  = "OnlineSync"
+	ModalityOnlineAsync = "OnlineAsync"
+	ModalityUnspecified = "Unspecified"
+	ModalityUnknown     = "Unknown" // meeting string could not be parsed
+)
+
+// Day codes used in meeting strings, in weekday order.
+var meetingDays = []string{"M", "Tu", "W", "Th", "F", "Sa", "Su"}
+
+// A Meeting is the structured form of a section's meeting string. Meeting
+// strings have one of the following formats:
+//   - In-person, synchronous: "Days-StartTime-EndTime-Building-Room"
+//   - Online, synchronous: "Days-StartTime-EndTime-OnlineSync"
+//   - Online, asynchronous: "OnlineAsync"
+//   - Unspecified: "Unspecified"
+//
+// Fields that do not apply to a meeting's modality are null.
+type Meeting struct {
+	// The original meeting string.
+	Raw string `json:"raw"`
+
+	// One of InPerson, OnlineSync, OnlineAsync, Unspecified, or Unknown.
+	Modality string `json:"modality"`
+
+	// Day codes (M, Tu, W, Th, F, Sa, Su) the meeting occurs on.
+	Days []string `json:"days"`
+
+	// Start and end times of the meeting in minutes after midnight.
+	StartMinutes *int `json:"start_minutes"`
+	EndMinutes   *int `json:"end_minutes"`
+
+	// Building code and room number for in-person meetings.
+	Building *string `json:"building"`
+	Room     *string `json:"room"`
+}
+
+// Parse each of a section's meeting strings.
+func parseMeetings(raw []string) []Meeting {
+	if raw == nil {
+		return nil
+	}
+	meetings := make([]Meeting, len(raw))
+	for i, r := range raw {
+		meetings[i] = parseMeeting(r)
+	}
+	return meetings
+}
+
+// Parse a meeting string into a Meeting. Strings that don't match a known
+// format are returned with the Unknown modality rather than an error, so one
+// malformed meeting doesn't hide the rest of a section.
+func parseMeeting(raw string) Meeting {
+	meeting := Meeting{Raw: raw, Modality: ModalityUnknown}
+	trimmed := strings.TrimSpace(raw)
+	switch trimmed {
+	case ModalityOnlineAsync, ModalityUnspecified:
+		meeting.Modality = trimmed
+		return meeting
+	}
+
+	// Rooms may themselves contain hyphens, so only split off the first four
+	parts := strings.SplitN(trimmed, "-", 5)
+	if len(parts) < 3 {
+		return meeting
+	}
+	days, err := parseDays(parts[0])
+	if err != nil {
+		return meeting
+	}
+	start, err := parseClockTime(parts[1])
+	if err != nil {
+		return meeting
+	}
+	end, err := parseClockTime(parts[2])
+	if err != nil {
+		return meeting
+	}
+	meeting.Days = days
+	meeting.StartMinutes = &start
+	meeting.EndMinutes = &end
+
+	switch {
+	case len(parts) == 4 && parts[3] == ModalityOnlineSync:
+		meeting.Modality = ModalityOnlineSync
+	case len(parts) == 5:
+		meeting.Modality = ModalityInPerson
+		meeting.Building = &parts[3]
+		meeting.Room = &parts[4]
+	case len(parts) == 4:
+		meeting.Modality = ModalityInPerson
+		meeting.Building = &parts[3]
+	default:
+		meeting.Modality = ModalityInPerson
+	}
+	return meeting
+}
+
+// Parse a string of concatenated day codes, such as "MWF" or "TuTh".
+func parseDays(s string) ([]string, error) {
+	days := []string{}
+	for len(s) > 0 {
+		matched := false
+		// Two-letter codes are checked first so "Tu" isn't read as an unknown "T"
+		for _, length := range []int{2, 1} {
+			if len(s) < length {
+				continue
+			}
+			for _, day := range meetingDays {
+				if len(day) == length && s[:length] == day {
+					days = append(days, day)
+					s = s[length:]
+					matched = true
+					break
+				}
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("unknown day code at %q", s)
+		}
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no days given")
+	}
+	return days, nil
+}
+
+// Parse a 12-hour clock time such as "9:30am" or "12:15pm" into minutes
+// after midnight.
+func parseClockTime(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var pm bool
+	switch {
+	case strings.HasSuffix(s, "am"):
+		s = strings.TrimSuffix(s, "am")
+	case strings.HasSuffix(s, "pm"):
+		s = strings.TrimSuffix(s, "pm")
+		pm = true
+	default:
+		return 0, fmt.Errorf("missing am/pm in time %q", s)
+	}
+	hourStr, minuteStr, ok := strings.Cut(s, ":")
+	if !ok {
+		minuteStr = "0"
+	}
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 1 || hour > 12 {
+		return 0, fmt.Errorf("invalid hour in time %q", s)
+	}
+	minute, err := strconv.Atoi(minuteStr)
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid minute in time %q", s)
+	}
+	hour %= 12
+	if pm {
+		hour += 12
+	}
+	return hour*60 + minute, nil
+}
+
+// Decode a section and parse its meeting strings, so every DataSource returns
+// sections with structured meetings alongside the raw strings.
+func (s *Section) UnmarshalJSON(data []byte) error {
+	type section Section // avoids recursing into this method
+	if err := json.Unmarshal(data, (*section)(s)); err != nil {
+		return err
+	}
+	s.ParsedMeetings = parseMeetings(s.Meetings)
+	return nil
+}
+
+/* ================================ FILTERS ================================ */
+
+// Query parameters for filtering sections by when they meet. Meetings without
+// a set time (asynchronous or unspecified) never violate these filters.
+type MeetingFilter struct {
+	// Only sections meeting exclusively on these days; for example, MWF
+	Days string `form:"days"`
+
+	// Only sections that don't meet on any of these days; for example, F
+	ExcludeDays string `form:"excludeDays"`
+
+	// Only sections whose meetings all start at or after this time; for
+	// example, 10:00 or 10:00am
+	StartAfter string `form:"startAfter"`
+
+	// Only sections whose meetings all end at or before this time; for
+	// example, 15:00 or 3:00pm
+	EndBefore string `form:"endBefore"`
+}
+
+// Get the names of the query args set in the filter.
+func (f MeetingFilter) params() []string {
+	params := []string{}
+	for _, param := range []struct{ name, value string }{
+		{"days", f.Days}, {"excludeDays", f.ExcludeDays}, {"startAfter", f.StartAfter}, {"endBefore", f.EndBefore},
+	} {
+		if param.value != "" {
+			params = append(params, param.name)
+		}
+	}
+	return params
+}
+
+// The parsed form of a MeetingFilter.
+type meetingConstraints struct {
+	allowedDays  dayMask
+	startAfter   int // minutes after midnight
+	endBefore    int // minutes after midnight
+	isConfigured bool
+}
+
+// A set of days, with one bit per entry of `meetingDays`.
+type dayMask uint8
+
+const allDays dayMask = 1<<7 - 1
+
+func dayMaskOf(days []string) dayMask {
+	var mask dayMask
+	for _, day := range days {
+		for i, d := range meetingDays {
+			if d == day {
+				mask |= 1 << i
+			}
+		}
+	}
+	return mask
+}
+
+// Validate and parse the filter's query parameters.
+func (f MeetingFilter) parse() (meetingConstraints, error) {
+	c := meetingConstraints{allowedDays: allDays, startAfter: 0, endBefore: 24 * 60}
+	if f.Days != "" {
+		days, err := parseDays(f.Days)
+		if err != nil {
+			return c, &paramError{"days", err}
+		}
+		c.allowedDays &= dayMaskOf(days)
+		c.isConfigured = true
+	}
+	if f.ExcludeDays != "" {
+		days, err := parseDays(f.ExcludeDays)
+		if err != nil {
+			return c, &paramError{"excludeDays", err}
+		}
+		c.allowedDays &^= dayMaskOf(days)
+		c.isConfigured = true
+	}
+	if f.StartAfter != "" {
+		minutes, err := parseFilterTime(f.StartAfter)
+		if err != nil {
+			return c, &paramError{"startAfter", err}
+		}
+		c.startAfter = minutes
+		c.isConfigured = true
+	}
+	if f.EndBefore != "" {
+		minutes, err := parseFilterTime(f.EndBefore)
+		if err != nil {
+			return c, &paramError{"endBefore", err}
+		}
+		c.endBefore = minutes
+		c.isConfigured = true
+	}
+	return c, nil
+}
+
+// Parse a time given as a query parameter, either in 24-hour format (15:00)
+// or in the 12-hour format used by meeting strings (3:00pm).
+func parseFilterTime(s string) (int, error) {
+	lower := strings.ToLower(s)
+	if strings.HasSuffix(lower, "am") || strings.HasSuffix(lower, "pm") {
+		return parseClockTime(lower)
+	}
+	hourStr, minuteStr, ok := strings.Cut(s, ":")
+	if !ok {
+		minuteStr = "0"
+	}
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("invalid hour in time %q", s)
+	}
+	minute, err := strconv.Atoi(minuteStr)
+	if err != nil || minute < 0 || minute > 59 || (hour == 24 && minute > 0) {
+		return 0, fmt.Errorf("invalid minute in time %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// Check whether every meeting of `section` satisfies the constraints. Sections
+// with meetings that could not be parsed are excluded, since there's no way to
+// tell whether they fit.
+func (c meetingConstraints) allows(section Section) bool {
+	for _, m := range section.ParsedMeetings {
+		switch m.Modality {
+		case ModalityOnlineAsync, ModalityUnspecified:
+			continue
+		case ModalityUnknown:
+			return false
+		}
+		if dayMaskOf(m.Days)&^c.allowedDays != 0 {
+			return false
+		}
+		if *m.StartMinutes < c.startAfter || *m.EndMinutes > c.endBefore {
+			return false
+		}
+	}
+	return true
+}
+
+// The number of rows requested from a DataSource per page when rows are
+// filtered after being fetched.
+const filteredPageSize = 500
+
+// Filters and sorts applied after fetching read every section matching the
+// rest of a request, so they are only allowed for a list of courses or at
+// least a department's worth, such as prefix=CMSC, rather than a whole term.
+const minFilterScopePrefix = 4
+
+// Check whether the course filters of a request narrow it enough to filter or
+// sort sections after fetching them.
+func isFilterScoped(courseCodes, prefix string) bool {
+	return courseCodes != "" || len(prefix) >= minFilterScopePrefix
+}
+
+// Fetch pages of rows from a DataSource until enough rows satisfy `keep` to
+// fill the page at `offset` with at most `limit` rows, or the source runs out.
+// This allows filters the backing store can't evaluate to be applied without
+// returning short pages.
+func fetchFiltered[T any](
+	offset, limit int, fetch func(offset, limit int) ([]T, error), keep func(*T) bool) ([]T, error) {
+	kept := []T{}
+	needed := offset + min(limit, math.MaxInt-offset)
+	for upstreamOffset := 0; len(kept) < needed; {
+		rows, err := fetch(upstreamOffset, filteredPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			if keep(&rows[i]) {
+				kept = append(kept, rows[i])
+			}
+		}
+		if len(rows) < filteredPageSize {
+			break
+		}
+		upstreamOffset += len(rows)
+	}
+	start := min(offset, len(kept))
+	end := min(needed, len(kept))
+	return kept[start:end], nil
+}
+
+// Get sections matching `args`, applying its MeetingFilter and instructor
+// rating filters after fetching. Sorting by rating needs every matching
+// section, so all of them are fetched before the page is taken; the number of
+// them is returned as the total, which is -1 otherwise.
+func fetchSections(
+	source DataSource, args SectionsArgs, constraints meetingConstraints, ratings sectionRatings) ([]Section, int, error) {
+	if !constraints.isConfigured && !ratings.isConfigured() {
+		sections, err := source.Sections(args)
+		return sections, -1, err
+	}
+	offset, limit := args.Offset, args.Limit
+	if ratings.sorted {
+		offset, limit = 0, math.MaxInt
+		if args.SortBy == "" {
+			// Break ties in rating consistently
+			args.SortBy = "course_code.asc,sec_code.asc"
+		}
+	}
+	sections, err := fetchFiltered(offset, limit, func(offset, limit int) ([]Section, error) {
+		page := args
+		page.Offset, page.Limit = offset, limit
+		return source.Sections(page)
+	}, func(s *Section) bool {
+		return (!constraints.isConfigured || constraints.allows(*s)) && (!ratings.isConfigured() || ratings.allows(s))
+	})
+	if err != nil || !ratings.sorted {
+		return sections, -1, err
+	}
+	ratings.sortSections(sections)
+	return paginate(sections, args.Offset, args.Limit), len(sections), nil
+}
+
+// Get courses with sections matching `args`, applying its MeetingFilter and
+// instructor rating filters to each course's sections after fetching. Courses
+// left without any sections are dropped, as with the other section filters.
+// The total is returned as with fetchSections.
+func fetchCoursesWithSections(source DataSource, args CoursesWithSectionsArgs,
+	constraints meetingConstraints, ratings sectionRatings) ([]CourseWithSections, int, error) {
+	if !constraints.isConfigured && !ratings.isConfigured() {
+		courses, err := source.CoursesWithSections(args)
+		return courses, -1, err
+	}
+	offset, limit := args.Offset, args.Limit
+	if ratings.sorted {
+		offset, limit = 0, math.MaxInt
+		if args.SortBy == "" {
+			args.SortBy = "course_code.asc"
+		}
+	}
+	filtersSections := constraints.isConfigured || ratings.min > 0
+	courses, err := fetchFiltered(offset, limit, func(offset, limit int) ([]CourseWithSections, error) {
+		page := args
+		page.Offset, page.Limit = offset, limit
+		return source.CoursesWithSections(page)
+	}, func(c *CourseWithSections) bool {
+		sections := []Section{}
+		for _, s := range c.Sections {
+			if (!constraints.isConfigured || constraints.allows(s)) && (!ratings.isConfigured() || ratings.allows(&s)) {
+				sections = append(sections, s)
+			}
+		}
+		c.Sections = sections
+		return len(sections) > 0 || !filtersSections
+	})
+	if err != nil || !ratings.sorted {
+		return courses, -1, err
+	}
+	ratings.sortCourses(courses)
+	return paginate(courses, args.Offset, args.Limit), len(courses), nil
+}
+
+// Count the sections matching `args`. Sections filtered after fetching can't
+// be counted by the data source, so all of them are fetched and counted.
+func countSections(
+	source DataSource, args SectionsArgs, constraints meetingConstraints, ratings sectionRatings) (int, error) {
+	if !constraints.isConfigured && ratings.min <= 0 {
+		return source.CountSections(args)
+	}
+	args.Offset, args.Limit = 0, math.MaxInt
+	sections, _, err := fetchSections(source, args, constraints, ratings)
+	return len(sections), err
+}
+
+// Count the courses with sections matching `args`, as with countSections.
+func countCoursesWithSections(source DataSource, args CoursesWithSectionsArgs,
+	constraints meetingConstraints, ratings sectionRatings) (int, error) {
+	if !constraints.isConfigured && ratings.min <= 0 {
+		return source.CountCoursesWithSections(args)
+	}
+	args.Offset, args.Limit = 0, math.MaxInt
+	courses, _, err := fetchCoursesWithSections(source, args, constraints, ratings)
+	return len(courses), err
+}
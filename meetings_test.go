@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMeeting(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{
+			raw: "TuTh-11:00am-12:15pm-CSI-1115",
+			want: `{"raw":"TuTh-11:00am-12:15pm-CSI-1115","modality":"InPerson","days":["Tu","Th"],` +
+				`"start_minutes":660,"end_minutes":735,"building":"CSI","room":"1115"}`,
+		},
+		{
+			raw: "MWF-12:00pm-12:50pm-ESJ-B0320",
+			want: `{"raw":"MWF-12:00pm-12:50pm-ESJ-B0320","modality":"InPerson","days":["M","W","F"],` +
+				`"start_minutes":720,"end_minutes":770,"building":"ESJ","room":"B0320"}`,
+		},
+		{
+			raw: "M-6:00pm-8:40pm-SHM-PRS-1114",
+			want: `{"raw":"M-6:00pm-8:40pm-SHM-PRS-1114","modality":"InPerson","days":["M"],` +
+				`"start_minutes":1080,"end_minutes":1240,"building":"SHM","room":"PRS-1114"}`,
+		},
+		{
+			raw: "Tu-9:30am-10:45am-OnlineSync",
+			want: `{"raw":"Tu-9:30am-10:45am-OnlineSync","modality":"OnlineSync","days":["Tu"],` +
+				`"start_minutes":570,"end_minutes":645,"building":null,"room":null}`,
+		},
+		{
+			raw: "SaSu-12:00am-11:59pm-ONLINE",
+			want: `{"raw":"SaSu-12:00am-11:59pm-ONLINE","modality":"InPerson","days":["Sa","Su"],` +
+				`"start_minutes":0,"end_minutes":1439,"building":"ONLINE","room":null}`,
+		},
+		{
+			raw: "TuTh-2pm-3:15pm",
+			want: `{"raw":"TuTh-2pm-3:15pm","modality":"InPerson","days":["Tu","Th"],` +
+				`"start_minutes":840,"end_minutes":915,"building":null,"room":null}`,
+		},
+		{
+			raw:  "OnlineAsync",
+			want: `{"raw":"OnlineAsync","modality":"OnlineAsync","days":null,"start_minutes":null,"end_minutes":null,"building":null,"room":null}`,
+		},
+		{
+			raw:  " Unspecified ",
+			want: `{"raw":" Unspecified ","modality":"Unspecified","days":null,"start_minutes":null,"end_minutes":null,"building":null,"room":null}`,
+		},
+		{
+			raw:  "TBA",
+			want: `{"raw":"TBA","modality":"Unknown","days":null,"start_minutes":null,"end_minutes":null,"building":null,"room":null}`,
+		},
+		{
+			raw:  "MTW-9:00am-9:50am-IRB-0324",
+			want: `{"raw":"MTW-9:00am-9:50am-IRB-0324","modality":"Unknown","days":null,"start_minutes":null,"end_minutes":null,"building":null,"room":null}`,
+		},
+		{
+			raw:  "MWF-13:00pm-1:50pm-IRB-0324",
+			want: `{"raw":"MWF-13:00pm-1:50pm-IRB-0324","modality":"Unknown","days":null,"start_minutes":null,"end_minutes":null,"building":null,"room":null}`,
+		},
+		{
+			raw:  "MWF-9:00-9:50am-IRB-0324",
+			want: `{"raw":"MWF-9:00-9:50am-IRB-0324","modality":"Unknown","days":null,"start_minutes":null,"end_minutes":null,"building":null,"room":null}`,
+		},
+		{
+			raw:  "",
+			want: `{"raw":"","modality":"Unknown","days":null,"start_minutes":null,"end_minutes":null,"building":null,"room":null}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			assertJSON(t, "meeting", parseMeeting(test.raw), json.RawMessage(test.want))
+		})
+	}
+}
+
+func TestMeetingFilterParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter MeetingFilter
+		want   meetingConstraints
+		err    string
+	}{
+		{
+			name: "none",
+			want: meetingConstraints{allowedDays: allDays, endBefore: 24 * 60},
+		},
+		{
+			name:   "days",
+			filter: MeetingFilter{Days: "MWF"},
+			want:   meetingConstraints{allowedDays: 0b10101, endBefore: 24 * 60, isConfigured: true},
+		},
+		{
+			name:   "days and excluded days",
+			filter: MeetingFilter{Days: "MTuWTh", ExcludeDays: "TuW"},
+			want:   meetingConstraints{allowedDays: 0b1001, endBefore: 24 * 60, isConfigured: true},
+		},
+		{
+			name:   "24-hour times",
+			filter: MeetingFilter{StartAfter: "10:00", EndBefore: "15:30"},
+			want:   meetingConstraints{allowedDays: allDays, startAfter: 600, endBefore: 930, isConfigured: true},
+		},
+		{
+			name:   "12-hour times",
+			filter: MeetingFilter{StartAfter: "9:30AM", EndBefore: "3pm"},
+			want:   meetingConstraints{allowedDays: allDays, startAfter: 570, endBefore: 900, isConfigured: true},
+		},
+		{
+			name:   "end of day",
+			filter: MeetingFilter{EndBefore: "24:00"},
+			want:   meetingConstraints{allowedDays: allDays, endBefore: 24 * 60, isConfigured: true},
+		},
+		{name: "unknown day", filter: MeetingFilter{Days: "MTW"}, err: `days: unknown day code at "TW"`},
+		{name: "unknown excluded day", filter: MeetingFilter{ExcludeDays: "X"}, err: `excludeDays: unknown day code at "X"`},
+		{name: "invalid hour", filter: MeetingFilter{StartAfter: "25:00"}, err: `startAfter: invalid hour in time "25:00"`},
+		{name: "past end of day", filter: MeetingFilter{EndBefore: "24:30"}, err: `endBefore: invalid minute in time "24:30"`},
+		{name: "invalid 12-hour time", filter: MeetingFilter{EndBefore: "13pm"}, err: `endBefore: invalid hour in time "13"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.filter.parse()
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("error = %v, want %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("constraints = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMeetingConstraintsAllows(t *testing.T) {
+	constraints, err := MeetingFilter{ExcludeDays: "F", StartAfter: "10:00", EndBefore: "15:00"}.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		meetings []string
+		want     bool
+	}{
+		{meetings: []string{"TuTh-11:00am-12:15pm-CSI-1115"}, want: true},
+		{meetings: []string{"MW-10:00am-10:50am-IRB-0324", "OnlineAsync"}, want: true},
+		{meetings: []string{"Unspecified"}, want: true},
+		{meetings: []string{}, want: true},
+		{meetings: []string{"TuTh-2:00pm-3:15pm-CSI-1115"}, want: false},
+		{meetings: []string{"MWF-11:00am-11:50am-IRB-0324"}, want: false},
+		{meetings: []string{"TuTh-9:30am-10:45am-OnlineSync"}, want: false},
+		{meetings: []string{"TuTh-11:00am-12:15pm-CSI-1115", "TBA"}, want: false},
+	}
+	for _, test := range tests {
+		section := Section{Meetings: test.meetings, ParsedMeetings: parseMeetings(test.meetings)}
+		if got := constraints.allows(section); got != test.want {
+			t.Errorf("allows(%q) = %t, want %t", test.meetings, got, test.want)
+		}
+	}
+}
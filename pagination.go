@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Arguments for paging through a list with cursors and counting the total
+// number of results. Embedded in the arguments of every list endpoint.
+type PageArgs struct {
+	// An opaque cursor for the page to get, taken from the Link or Next-Cursor
+	// header of a previous response; cannot be used with offset.
+	Cursor string `form:"cursor"`
+
+	// Set to exact to count the total number of results, which is sent in the
+	// Content-Range header.
+	Count string `form:"count" binding:"omitempty,oneof=exact"`
+}
+
+// The contents of a cursor. Cursors are tied to the query they were made for,
+// so a cursor can't be used to page through a different list by mistake.
+type pageCursor struct {
+	Offset int    `json:"o"`
+	Query  string `json:"q"`
+}
+
+// Query args that don't change which results are in a list, only which page
+// of them is returned.
+var pageQueryArgs = []string{"cursor", "offset", "limit", "count"}
+
+// Get a fingerprint of the query args of `r` that determine which results are
+// in a list.
+func queryFingerprint(r *http.Request) string {
+	query := r.URL.Query()
+	for _, arg := range pageQueryArgs {
+		query.Del(arg)
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	for _, k := range keys {
+		for _, v := range query[k] {
+			fmt.Fprintf(h, "%s=%s&", k, v)
+		}
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+func encodeCursor(offset int, fingerprint string) string {
+	data, _ := json.Marshal(pageCursor{Offset: offset, Query: fingerprint})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor, fingerprint string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("malformed cursor")
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Offset < 0 {
+		return 0, errors.New("malformed cursor")
+	}
+	if c.Query != fingerprint {
+		return 0, errors.New("cursor was made for a different query")
+	}
+	return c.Offset, nil
+}
+
+// Set `offset` from the cursor in `p`, if there is one. Sends an error to the
+// caller and returns false if the cursor is invalid or given with an offset.
+func (p PageArgs) resolveCursor(ctx *gin.Context, offset *int) bool {
+	if p.Cursor == "" {
+		return true
+	}
+	if ctx.Query("offset") != "" {
+		sendProblem(ctx, newInvalidParams(
+			"Cannot specify both cursor and offset",
+			InvalidParam{Name: "cursor", Reason: "cannot be used with offset"},
+			InvalidParam{Name: "offset", Reason: "cannot be used with cursor"},
+		))
+		return false
+	}
+	o, err := decodeCursor(p.Cursor, queryFingerprint(ctx.Request))
+	if err != nil {
+		sendProblem(ctx, newInvalidParamError(fmt.Sprintf("Invalid cursor: %s", err), "cursor", err))
+		return false
+	}
+	*offset = o
+	return true
+}
+
+// A page of results and where it is in the full list.
+type page struct {
+	offset, limit int
+
+	// Number of results on the page.
+	size int
+
+	// Total number of results, or -1 if they weren't counted.
+	total int
+}
+
+// Links to the pages around a page of the list requested by `r`, and the
+// cursor for the next page. Next is only set if there may be more results,
+// and Prev if the page isn't the first.
+type pageLinks struct {
+	Next, Prev, NextCursor string
+}
+
+func (p page) links(r *http.Request) pageLinks {
+	fingerprint := queryFingerprint(r)
+	link := func(offset int) string {
+		query := r.URL.Query()
+		query.Del("offset")
+		query.Set("cursor", encodeCursor(offset, fingerprint))
+		return r.URL.Path + "?" + query.Encode()
+	}
+
+	// A full page may be followed by more results, unless the count says not
+	var links pageLinks
+	next := p.offset + p.size
+	hasNext := p.size > 0 && p.size == p.limit
+	if p.total >= 0 {
+		hasNext = hasNext && next < p.total
+	}
+	if hasNext {
+		links.Next = link(next)
+		links.NextCursor = encodeCursor(next, fingerprint)
+	}
+	if p.offset > 0 {
+		links.Prev = link(max(p.offset-p.limit, 0))
+	}
+	return links
+}
+
+// Build the headers describing a page of the list requested by `r`: Link
+// headers (RFC 8288) to the next and previous pages and a Next-Cursor header
+// if there is a next page, and a Content-Range header with the total number
+// of results if they were counted, in the same format as PostgREST.
+func (p page) header(r *http.Request) http.Header {
+	header := http.Header{}
+	links := p.links(r)
+	if links.Next != "" {
+		header.Add("Link", fmt.Sprintf("<%s>; rel=\"next\"", links.Next))
+		header.Set("Next-Cursor", links.NextCursor)
+	}
+	if links.Prev != "" {
+		header.Add("Link", fmt.Sprintf("<%s>; rel=\"prev\"", links.Prev))
+	}
+	if p.total >= 0 {
+		if p.size == 0 {
+			header.Set("Content-Range", fmt.Sprintf("*/%d", p.total))
+		} else {
+			header.Set("Content-Range", fmt.Sprintf("%d-%d/%d", p.offset, p.offset+p.size-1, p.total))
+		}
+	}
+	return header
+}
+
+// Count the total number of results with `count` if requested in `p`.
+// Returns -1 if they weren't requested.
+func (p PageArgs) countTotal(count func() (int, error)) (int, error) {
+	if p.Count != "exact" {
+		return -1, nil
+	}
+	return count()
+}
+
+// Get the number of results in `result`, which should be a slice.
+func resultLen(result any) int {
+	v := reflect.ValueOf(result)
+	if v.Kind() != reflect.Slice {
+		return 0
+	}
+	return v.Len()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+		want   int
+		err    string
+	}{
+		{name: "round trip", cursor: encodeCursor(40, "abc"), want: 40},
+		{name: "first page", cursor: encodeCursor(0, "abc"), want: 0},
+		{name: "different query", cursor: encodeCursor(40, "xyz"), err: "cursor was made for a different query"},
+		{name: "not base64", cursor: "not a cursor!", err: "malformed cursor"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"o":1,"q":"abc"}`)), err: "malformed cursor"},
+		{name: "not JSON", cursor: encode("40"), err: "malformed cursor"},
+		{name: "wrong type", cursor: encode(`{"o":"40","q":"abc"}`), err: "malformed cursor"},
+		{name: "negative offset", cursor: encode(`{"o":-1,"q":"abc"}`), err: "malformed cursor"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodeCursor(test.cursor, "abc")
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("error = %v, want %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("offset = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestQueryFingerprint(t *testing.T) {
+	fingerprint := func(target string) string {
+		return queryFingerprint(httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	base := fingerprint("/v1/courses?prefix=CMSC&term=202508")
+	tests := []struct {
+		target string
+		same   bool
+	}{
+		{target: "/v1/courses?term=202508&prefix=CMSC", same: true},
+		{target: "/v1/courses?prefix=CMSC&term=202508&limit=5&offset=10&count=exact&cursor=abc", same: true},
+		{target: "/v1/courses?prefix=CMSC", same: false},
+		{target: "/v1/courses?prefix=MATH&term=202508", same: false},
+		{target: "/v1/courses?prefix=CMSC&term=202508&genEds=DSSP", same: false},
+		{target: "/v1/courses?prefix=CMSC&term=202508&term=202601", same: false},
+	}
+	for _, test := range tests {
+		if got := fingerprint(test.target) == base; got != test.same {
+			t.Errorf("fingerprint of %s matches: %t, want %t", test.target, got, test.same)
+		}
+	}
+}
+
+func TestPageHeader(t *testing.T) {
+	const target = "/v1/courses?prefix=CMSC&limit=2&offset=2"
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	fingerprint := queryFingerprint(r)
+	link := func(offset int, rel string) string {
+		return fmt.Sprintf(`</v1/courses?cursor=%s&limit=2&prefix=CMSC>; rel="%s"`, encodeCursor(offset, fingerprint), rel)
+	}
+	tests := []struct {
+		name         string
+		page         page
+		links        []string
+		nextCursor   string
+		contentRange string
+	}{
+		{
+			name:       "first page",
+			page:       page{offset: 0, limit: 2, size: 2, total: -1},
+			links:      []string{link(2, "next")},
+			nextCursor: encodeCursor(2, fingerprint),
+		},
+		{
+			name:         "middle page",
+			page:         page{offset: 2, limit: 2, size: 2, total: 5},
+			links:        []string{link(4, "next"), link(0, "prev")},
+			nextCursor:   encodeCursor(4, fingerprint),
+			contentRange: "2-3/5",
+		},
+		{
+			name:         "full last page",
+			page:         page{offset: 2, limit: 2, size: 2, total: 4},
+			links:        []string{link(0, "prev")},
+			contentRange: "2-3/4",
+		},
+		{
+			name:  "partial last page",
+			page:  page{offset: 3, limit: 2, size: 1, total: -1},
+			links: []string{link(1, "prev")},
+		},
+		{
+			name:         "empty",
+			page:         page{offset: 0, limit: 2, size: 0, total: 0},
+			contentRange: "*/0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := test.page.header(r)
+			if got, want := strings.Join(header.Values("Link"), "\n"), strings.Join(test.links, "\n"); got != want {
+				t.Errorf("Link = %s, want %s", got, want)
+			}
+			if got := header.Get("Next-Cursor"); got != test.nextCursor {
+				t.Errorf("Next-Cursor = %q, want %q", got, test.nextCursor)
+			}
+			if got := header.Get("Content-Range"); got != test.contentRange {
+				t.Errorf("Content-Range = %q, want %q", got, test.contentRange)
+			}
+		})
+	}
+}
+
+func TestCursors(t *testing.T) {
+	server := newTestServer(t)
+	w := serve(server, http.MethodGet, "/v1/courses?prefix=CMSC&limit=2", "")
+	cursor := w.Header().Get("Next-Cursor")
+	if w.Code != http.StatusOK || cursor == "" {
+		t.Fatalf("status = %d, Next-Cursor = %q; want a cursor for the next page: %s", w.Code, cursor, w.Body)
+	}
+	tests := []struct {
+		target string
+		status int
+	}{
+		{target: "/v1/courses?prefix=CMSC&limit=2&cursor=" + cursor, status: http.StatusOK},
+		{target: "/v1/courses?prefix=CMSC&limit=5&count=exact&cursor=" + cursor, status: http.StatusOK},
+		{target: "/v1/courses?prefix=MATH&limit=2&cursor=" + cursor, status: http.StatusBadRequest},
+		{target: "/v1/courses?prefix=CMSC&limit=2&offset=2&cursor=" + cursor, status: http.StatusBadRequest},
+		{target: "/v1/courses?prefix=CMSC&limit=2&cursor=bogus", status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if w := serve(server, http.MethodGet, test.target, ""); w.Code != test.status {
+			t.Errorf("GET %s: status = %d, want %d: %s", test.target, w.Code, test.status, w.Body)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "strings"
+
+// Values from query args are encoded with these functions before they're put
+// in a PostgREST filter, so they're always read as a single value and can't
+// change the structure of the query. Query params are URL-encoded separately,
+// so values can't add params either; only characters PostgREST itself treats
+// specially need escaping.
+
+// Quote a value for a PostgREST list or array. In quotes, commas, parentheses,
+// and braces are literal; quotes and backslashes are escaped with a backslash.
+func postgrestQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// Format values as a PostgREST list for the `in` operator, such as
+// ("CMSC131","CMSC132").
+func postgrestList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = postgrestQuote(v)
+	}
+	return "(" + strings.Join(quoted, ",") + ")"
+}
+
+// Format values as a PostgREST array literal for the `cs` and `ov` operators,
+// such as {"Ali Abasi","Daniel Abadi"}.
+func postgrestArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = postgrestQuote(v)
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+// Escape a value to match literally in a `like` pattern, so wildcards can
+// only come from the pattern around it. `%` and `_` are escaped for Postgres.
+// PostgREST turns every `*` into `%` before Postgres sees the pattern, so an
+// escaped `*` matches a literal `%`; either way, it isn't a wildcard.
+func postgrestLikeLiteral(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	for _, special := range []string{"%", "_", "*"} {
+		value = strings.ReplaceAll(value, special, `\`+special)
+	}
+	return value
+}
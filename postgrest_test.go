@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Values that PostgREST or Postgres treat specially in lists, arrays, or like
+// patterns.
+var trickyValues = []string{
+	"CMSC131",
+	"",
+	" ",
+	"a,b",
+	"(CMSC131)",
+	"CMSC131),course_code.eq.(x",
+	"{a,b}",
+	"a}",
+	`"`,
+	`""`,
+	`\`,
+	`\"`,
+	`a\,b`,
+	`","`,
+	"%",
+	"_",
+	"*",
+	`\%`,
+	"CMSC%_*",
+	"null",
+	"Terrence O’Brien",
+	"a\nb",
+}
+
+func TestPostgrestList(t *testing.T) {
+	for _, value := range trickyValues {
+		checkElements(t, postgrestList([]string{value}), '(', ')', []string{value})
+	}
+	checkElements(t, postgrestList(trickyValues), '(', ')', trickyValues)
+	if got := postgrestList([]string{"CMSC131", "CMSC132"}); got != `("CMSC131","CMSC132")` {
+		t.Errorf(`postgrestList = %s, want ("CMSC131","CMSC132")`, got)
+	}
+}
+
+func TestPostgrestArray(t *testing.T) {
+	for _, value := range trickyValues {
+		checkElements(t, postgrestArray([]string{value}), '{', '}', []string{value})
+	}
+	checkElements(t, postgrestArray(trickyValues), '{', '}', trickyValues)
+	if got := postgrestArray([]string{"Ali Abasi", `O"Brien`}); got != `{"Ali Abasi","O\"Brien"}` {
+		t.Errorf(`postgrestArray = %s, want {"Ali Abasi","O\"Brien"}`, got)
+	}
+}
+
+func TestPostgrestLikeLiteral(t *testing.T) {
+	for _, value := range trickyValues {
+		checkLikeLiteral(t, value)
+	}
+	for value, want := range map[string]string{
+		"CMSC":  "CMSC",
+		"1%":    `1\%`,
+		"_31":   `\_31`,
+		"13*":   `13\*`,
+		`a\b`:   `a\\b`,
+		`\%`:    `\\\%`,
+		"CMSC_": `CMSC\_`,
+	} {
+		if got := postgrestLikeLiteral(value); got != want {
+			t.Errorf("postgrestLikeLiteral(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func FuzzPostgrestList(f *testing.F) {
+	for _, value := range trickyValues {
+		f.Add(value, "CMSC131")
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		checkElements(t, postgrestList([]string{a}), '(', ')', []string{a})
+		checkElements(t, postgrestList([]string{a, b}), '(', ')', []string{a, b})
+	})
+}
+
+func FuzzPostgrestArray(f *testing.F) {
+	for _, value := range trickyValues {
+		f.Add(value, "Ali Abasi")
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		checkElements(t, postgrestArray([]string{a}), '{', '}', []string{a})
+		checkElements(t, postgrestArray([]string{a, b}), '{', '}', []string{a, b})
+	})
+}
+
+func FuzzPostgrestLikeLiteral(f *testing.F) {
+	for _, value := range trickyValues {
+		f.Add(value)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		checkLikeLiteral(t, value)
+	})
+}
+
+// Check that `encoded` is read by PostgREST as a list or array of exactly the
+// values in `want`.
+func checkElements(t *testing.T, encoded string, open, close byte, want []string) {
+	t.Helper()
+	got, err := parseElements(encoded, open, close)
+	if err != nil {
+		t.Fatalf("%s: %s", encoded, err)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("%s was read as %q, want %q", encoded, got, want)
+	}
+}
+
+// Parse a list such as ("a","b") or an array such as {"a","b"} the way
+// PostgREST does: elements are separated by commas outside of quotes, and in
+// quotes, a backslash escapes the next character. Only quoted elements are
+// accepted, since every element should be quoted.
+func parseElements(s string, open, close byte) ([]string, error) {
+	if len(s) < 2 || s[0] != open || s[len(s)-1] != close {
+		return nil, fmt.Errorf("not enclosed in %c%c", open, close)
+	}
+	s = s[1 : len(s)-1]
+	elements := []string{}
+	for i := 0; ; {
+		if i >= len(s) || s[i] != '"' {
+			return nil, fmt.Errorf("unquoted element at %d", i)
+		}
+		var b strings.Builder
+		i++
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' {
+				i++
+				if i == len(s) {
+					return nil, fmt.Errorf("dangling backslash")
+				}
+			}
+			b.WriteByte(s[i])
+		}
+		if i == len(s) {
+			return nil, fmt.Errorf("unterminated quote")
+		}
+		elements = append(elements, b.String())
+		i++
+		if i == len(s) {
+			return elements, nil
+		}
+		if s[i] != ',' {
+			return nil, fmt.Errorf("unexpected %q after element at %d", s[i], i)
+		}
+		i++
+	}
+}
+
+// Check that the like literal for `value` has no wildcards and matches
+// exactly `value`.
+func checkLikeLiteral(t *testing.T, value string) {
+	t.Helper()
+	encoded := postgrestLikeLiteral(value)
+	var b strings.Builder
+	for i := 0; i < len(encoded); i++ {
+		switch encoded[i] {
+		case '\\':
+			i++
+			if i == len(encoded) {
+				t.Fatalf("%q: dangling backslash", encoded)
+			}
+		case '%', '_', '*':
+			t.Fatalf("%q: unescaped wildcard %c at %d", encoded, encoded[i], i)
+		}
+		b.WriteByte(encoded[i])
+	}
+	if b.String() != value {
+		t.Fatalf("%q matches %q, want %q", encoded, b.String(), value)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import "sort"
+
+// Arguments for traversing the prerequisite graph.
+type PrerequisiteArgs struct {
+	// How many levels of prerequisites to expand; 1 returns only the course's
+	// direct prerequisites.
+	// Default value: 3; Maximum value: 10
+	Depth uint8 `form:"depth" binding:"omitempty,min=1,max=10"`
+
+	// The term (semester) to get data for, as YYYYMM; for example, 202601.
+	// Default value: the current default term
+	Term string `form:"term" binding:"omitempty,len=6,numeric"`
+}
+
+func (p *PrerequisiteArgs) setDefaults() {
+	if p.Depth == 0 {
+		p.Depth = 3
+	}
+}
+
+// A course in the prerequisite graph, along with its parsed prerequisites.
+type graphCourse struct {
+	Name          string       `json:"name"`
+	Prerequisites *Requirement `json:"prerequisites"`
+}
+
+// Courses in a term by course code; each course has edges to the courses in
+// its prerequisites.
+type prerequisiteGraph map[string]graphCourse
+
+// A course's prerequisites, where each course in the requirement tree has its
+// own prerequisites expanded up to the requested depth.
+type PrerequisiteTree struct {
+	CourseCode    string       `json:"course_code"`
+	Name          string       `json:"name"`
+	Prerequisites *Requirement `json:"prerequisites"`
+}
+
+// A course that lists another course as a prerequisite.
+type UnlockedCourse struct {
+	CourseCode string `json:"course_code"`
+	Name       string `json:"name"`
+
+	// The minimum grade required in the prerequisite, if any.
+	MinGrade string `json:"min_grade,omitempty"`
+}
+
+// Get every course in `term`, one page at a time.
+func fetchAllCourses(source DataSource, term string) ([]Course, error) {
+	all := []Course{}
+	for offset := 0; ; offset += filteredPageSize {
+		courses, err := source.Courses(CoursesArgs{
+			Term:   term,
+			Limit:  filteredPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, courses...)
+		if len(courses) < filteredPageSize {
+			break
+		}
+	}
+	return all, nil
+}
+
+// Build the prerequisite graph for `term` from every course in that term.
+func buildPrerequisiteGraph(source DataSource, term string) (prerequisiteGraph, error) {
+	courses, err := fetchAllCourses(source, term)
+	if err != nil {
+		return nil, err
+	}
+	graph := prerequisiteGraph{}
+	for _, course := range courses {
+		entry := graphCourse{Name: course.Name}
+		if conditions := parseConditions(course.Conditions); conditions != nil {
+			entry.Prerequisites = conditions.Prerequisites
+		}
+		graph[course.CourseCode] = entry
+	}
+	return graph, nil
+}
+
+// Get the prerequisite graph for `term`, from the cache if possible. Graphs
+// are built from every course in a term, so they're cached separately from any
+// one response.
+func (server Server) getPrerequisiteGraph(term string) (prerequisiteGraph, error) {
+	return server.prerequisiteGraphs.get(term, func() (prerequisiteGraph, error) {
+		return buildPrerequisiteGraph(server.source, term)
+	})
+}
+
+// Get the prerequisite tree of `code`, expanding the prerequisites of each
+// course in the tree up to `depth` levels. Courses that are already being
+// expanded higher up in the tree aren't expanded again, so cycles terminate.
+func (graph prerequisiteGraph) prerequisiteTree(code string, depth int) PrerequisiteTree {
+	course := graph[code]
+	return PrerequisiteTree{
+		CourseCode:    code,
+		Name:          course.Name,
+		Prerequisites: graph.expand(course.Prerequisites, depth-1, map[string]bool{code: true}),
+	}
+}
+
+func (graph prerequisiteGraph) expand(
+	requirement *Requirement, depth int, ancestors map[string]bool) *Requirement {
+	if requirement == nil {
+		return nil
+	}
+	expanded := *requirement
+	if len(requirement.Children) > 0 {
+		expanded.Children = make([]Requirement, len(requirement.Children))
+		for i := range requirement.Children {
+			expanded.Children[i] = *graph.expand(&requirement.Children[i], depth, ancestors)
+		}
+	}
+	code := requirement.CourseCode
+	if requirement.Type == RequirementCourse && depth > 0 && !ancestors[code] {
+		if course, ok := graph[code]; ok {
+			ancestors[code] = true
+			expanded.Prerequisites = graph.expand(course.Prerequisites, depth-1, ancestors)
+			delete(ancestors, code)
+		}
+	}
+	return &expanded
+}
+
+// Get the courses that list `code` as a prerequisite, ordered by course code.
+func (graph prerequisiteGraph) unlocks(code string) []UnlockedCourse {
+	unlocked := []UnlockedCourse{}
+	for other, course := range graph {
+		if node := findCourse(course.Prerequisites, code); node != nil {
+			unlocked = append(unlocked, UnlockedCourse{
+				CourseCode: other,
+				Name:       course.Name,
+				MinGrade:   node.MinGrade,
+			})
+		}
+	}
+	sort.Slice(unlocked, func(i, j int) bool {
+		return unlocked[i].CourseCode < unlocked[j].CourseCode
+	})
+	return unlocked
+}
+
+// Find the first node in a requirement tree for the course `code`.
+func findCourse(requirement *Requirement, code string) *Requirement {
+	if requirement == nil {
+		return nil
+	}
+	if requirement.Type == RequirementCourse && requirement.CourseCode == code {
+		return requirement
+	}
+	for i := range requirement.Children {
+		if node := findCourse(&requirement.Children[i], code); node != nil {
+			return node
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Media type of v1 error responses (RFC 7807).
+const problemContentType = "application/problem+json"
+
+// Problem types. A problem's type URI is the type appended to
+// `problemTypeBase`; each type is described in the Errors section of the docs.
+const (
+	problemTypeBase = "https://api.jupiterp.com/problems/"
+
+	// A query or path parameter is missing or invalid
+	problemInvalidParams = "invalid-parameters"
+
+	// The request body is missing or invalid
+	problemInvalidBody = "invalid-body"
+
+	// The requested resource doesn't exist
+	problemNotFound = "not-found"
+
+	// The database rejected the query or failed
+	problemUpstream = "upstream-error"
+
+	// Something unexpected went wrong in the API
+	problemInternal = "internal-error"
+)
+
+var problemTitles = map[string]string{
+	problemInvalidParams: "Invalid parameters",
+	problemInvalidBody:   "Invalid request body",
+	problemNotFound:      "Not found",
+	problemUpstream:      "Database error",
+	problemInternal:      "Internal server error",
+}
+
+// An error response. v1 sends problems as application/problem+json (RFC
+// 7807); v0 only sends the detail, as {"error": detail}, so its errors are
+// unchanged.
+type Problem struct {
+	// URI identifying the type of problem; see `problemTypeBase`
+	Type string `json:"type"`
+
+	// Short summary of the type of problem, which is the same for every
+	// problem of a type
+	Title string `json:"title"`
+
+	// HTTP status code
+	Status int `json:"status"`
+
+	// Explanation of this occurrence of the problem
+	Detail string `json:"detail"`
+
+	// Path and query of the request
+	Instance string `json:"instance"`
+
+	// Each parameter that is missing or invalid, for invalid-parameters
+	// problems
+	InvalidParams []InvalidParam `json:"invalidParams,omitempty"`
+
+	// ID of the request, also sent in the X-Request-Id header; include it when
+	// reporting a bug
+	RequestID string `json:"requestId"`
+
+	// The error sent to v0 callers, if it isn't the detail
+	legacyError string
+}
+
+// A missing or invalid parameter in a Problem.
+type InvalidParam struct {
+	// Name of the query parameter, path parameter, or field of the body
+	Name string `json:"name"`
+
+	// Why the parameter is invalid
+	Reason string `json:"reason"`
+}
+
+// An error in the value of a single parameter; `newInvalidParams` lists the
+// parameter in the problem.
+type paramError struct {
+	name string
+	err  error
+}
+
+func (e *paramError) Error() string {
+	return e.name + ": " + e.err.Error()
+}
+
+func (e *paramError) Unwrap() error {
+	return e.err
+}
+
+func newProblem(problemType string, status int, detail string) Problem {
+	return Problem{
+		Type:   problemTypeBase + problemType,
+		Title:  problemTitles[problemType],
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Build an invalid-parameters problem listing `params`.
+func newInvalidParams(detail string, params ...InvalidParam) Problem {
+	problem := newProblem(problemInvalidParams, http.StatusBadRequest, detail)
+	problem.InvalidParams = params
+	return problem
+}
+
+// Build an invalid-parameters problem from an error in the value of `name`,
+// or of the parameter named by `err` if it is a *paramError.
+func newInvalidParamError(detail, name string, err error) Problem {
+	reason := err.Error()
+	var paramErr *paramError
+	if errors.As(err, &paramErr) {
+		name, reason = paramErr.name, paramErr.err.Error()
+	}
+	return newInvalidParams(detail, InvalidParam{Name: name, Reason: reason})
+}
+
+// Build a problem from an error returned by the database, using the message
+// from the body as the detail if there is one.
+func newUpstreamProblem(upstreamErr *UpstreamError) Problem {
+	detail := strings.TrimSpace(string(upstreamErr.Body))
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(upstreamErr.Body, &body) == nil && body.Message != "" {
+		detail = body.Message
+	}
+	return newProblem(problemUpstream, upstreamErr.Status, detail)
+}
+
+// Send `problem` to the caller, in the format of the API version requested.
+func sendProblem(ctx *gin.Context, problem Problem) {
+	var payload *cachedPayload
+	var err error
+	if isV1(ctx) {
+		payload, err = buildProblemPayload(ctx, problem)
+	} else {
+		// Encoded as by ctx.JSON, which v0 errors have always been sent with
+		var body []byte
+		body, err = json.Marshal(gin.H{"error": problem.v0Error()})
+		payload = &cachedPayload{status: problem.Status, header: http.Header{}, body: body}
+		payload.header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	if err != nil {
+		ctx.AbortWithStatus(problem.Status)
+		return
+	}
+	setNoStore(payload)
+	writePayload(ctx, payload, ctx.Request.URL.Path)
+}
+
+// Build a payload from `problem` for caching, in the format of the API version
+// requested. The instance and request ID of v1 problems are filled in when the
+// payload is written, so that cached problems have those of each request.
+func buildProblemPayload(ctx *gin.Context, problem Problem) (*cachedPayload, error) {
+	if !isV1(ctx) {
+		return buildJSONPayload(problem.Status, gin.H{"error": problem.v0Error()})
+	}
+	payload, err := buildJSONPayload(problem.Status, problem)
+	if err != nil {
+		return nil, err
+	}
+	payload.header.Set("Content-Type", problemContentType)
+	return payload, nil
+}
+
+// Build a payload from an upstream error, in the format of the API version
+// requested. v0 passes through the status and body the database returned.
+func buildUpstreamErrorPayload(ctx *gin.Context, upstreamErr *UpstreamError) (*cachedPayload, error) {
+	if !isV1(ctx) {
+		return buildPayloadFromUpstreamError(upstreamErr), nil
+	}
+	return buildProblemPayload(ctx, newUpstreamProblem(upstreamErr))
+}
+
+func (p Problem) v0Error() string {
+	if p.legacyError != "" {
+		return p.legacyError
+	}
+	return p.Detail
+}
+
+// Fill in the instance and request ID of a problem payload for the request in
+// `ctx`. Other payloads are returned as-is.
+func stampProblemPayload(ctx *gin.Context, payload *cachedPayload) *cachedPayload {
+	if payload.header.Get("Content-Type") != problemContentType {
+		return payload
+	}
+	var problem Problem
+	if err := json.Unmarshal(payload.body, &problem); err != nil {
+		return payload
+	}
+	problem.Instance = requestPath(ctx.Request)
+	problem.RequestID = ctx.GetString(requestIDKey)
+	stamped, err := buildJSONPayload(payload.status, problem)
+	if err != nil {
+		return payload
+	}
+	stamped.header = payload.header
+	return stamped
+}
+
+/* =============================== REQUEST IDS ============================== */
+
+const (
+	requestIDHeader = "X-Request-Id"
+
+	// Key in the gin context of the ID of the request
+	requestIDKey = "requestID"
+)
+
+// Request IDs accepted from callers, so a request can be traced through a
+// proxy; others are replaced with a new ID.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Middleware that gives each request an ID, sent in the X-Request-Id header
+// and in problems, and logged with internal errors. An X-Request-Id sent by
+// the caller is kept.
+func requestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		ctx.Set(requestIDKey, id)
+		ctx.Header(requestIDHeader, id)
+		ctx.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
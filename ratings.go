@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sort column for ordering sections by the ratings of their instructors. It
+// isn't a column in the database, so sections are sorted after fetching.
+const instructorRatingColumn = "instructor_rating"
+
+// Filters and ordering on the ratings of sections' instructors, parsed from
+// query args. A section's rating is the average rating of its instructors
+// that have ratings on PlanetTerp; sections without any are unrated.
+type sectionRatings struct {
+	linker *instructorLinker
+
+	// Minimum rating of sections to keep; unrated sections are dropped if set.
+	min float64
+
+	// Whether to sort by rating, and in which direction. Unrated sections are
+	// last in either direction.
+	sorted bool
+	desc   bool
+}
+
+// Parse the instructor_rating column out of a sortBy string, returning the
+// rest of the string to pass on to the data source. instructor_rating must be
+// the first column, as later columns only break ties within it.
+func parseRatingSort(sortBy string) (rest string, sorted, desc bool, err error) {
+	columns := strings.Split(sortBy, ",")
+	for i, column := range columns {
+		name, direction, _ := strings.Cut(strings.TrimSpace(column), ".")
+		if name != instructorRatingColumn {
+			continue
+		}
+		if i != 0 {
+			return "", false, false, fmt.Errorf("%s must be the first column", instructorRatingColumn)
+		}
+		switch direction {
+		case "", "asc":
+		case "desc":
+			desc = true
+		default:
+			return "", false, false, fmt.Errorf("invalid order %q for %s; use asc or desc",
+				direction, instructorRatingColumn)
+		}
+		sorted = true
+	}
+	if !sorted {
+		return sortBy, false, false, nil
+	}
+	return strings.Join(columns[1:], ","), true, desc, nil
+}
+
+func (r sectionRatings) isConfigured() bool {
+	return r.min > 0 || r.sorted
+}
+
+// Set the instructor rating of a section and check it against the minimum.
+func (r sectionRatings) allows(s *Section) bool {
+	sum, count := 0.0, 0
+	for _, name := range s.Instructors {
+		if instructor := r.linker.link(name); instructor != nil && instructor.AverageRating != nil {
+			sum += *instructor.AverageRating
+			count++
+		}
+	}
+	s.InstructorRating = nil
+	if count > 0 {
+		average := sum / float64(count)
+		s.InstructorRating = &average
+	}
+	return r.min <= 0 || s.InstructorRating != nil && *s.InstructorRating >= r.min
+}
+
+// Check whether section a is ordered before section b; both must be rated
+// with `allows` first.
+func (r sectionRatings) less(a, b Section) bool {
+	ra, rb := a.InstructorRating, b.InstructorRating
+	if ra == nil || rb == nil {
+		return ra != nil && rb == nil
+	}
+	if r.desc {
+		return *ra > *rb
+	}
+	return *ra < *rb
+}
+
+// Sort sections by rating. The sort is stable, so sections with the same
+// rating keep the order they were fetched in.
+func (r sectionRatings) sortSections(sections []Section) {
+	sort.SliceStable(sections, func(i, j int) bool {
+		return r.less(sections[i], sections[j])
+	})
+}
+
+// Sort the sections of each course by rating, then sort courses by the rating
+// of their first section, which is their best in the sort direction.
+func (r sectionRatings) sortCourses(courses []CourseWithSections) {
+	for _, course := range courses {
+		r.sortSections(course.Sections)
+	}
+	sort.SliceStable(courses, func(i, j int) bool {
+		a, b := courses[i].Sections, courses[j].Sections
+		if len(a) == 0 || len(b) == 0 {
+			return len(a) > 0 && len(b) == 0
+		}
+		return r.less(a[0], b[0])
+	})
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Maximum number of sections that can be checked in a single schedule.
+const maxScheduleSections = 30
+
+// A reference to a single section of a course.
+type SectionRef struct {
+	CourseCode string `json:"course_code"`
+	SecCode    string `json:"sec_code"`
+}
+
+func refOf(s Section) SectionRef {
+	return SectionRef{CourseCode: s.CourseCode, SecCode: s.SecCode}
+}
+
+// Body of a request to check a schedule.
+type ScheduleCheckRequest struct {
+	// The term (semester) the sections are in; defaults to the default term.
+	Term string `json:"term"`
+
+	// The sections in the schedule.
+	Sections []SectionRef `json:"sections"`
+}
+
+// A pair of meetings from different sections that overlap in time.
+type MeetingConflict struct {
+	Sections [2]SectionRef `json:"sections"`
+	Meetings [2]string     `json:"meetings"`
+	Days     []string      `json:"days"`
+}
+
+// The result of checking a schedule.
+type ScheduleCheckResult struct {
+	// Sections in the schedule that exist.
+	Sections []Section `json:"sections"`
+
+	// Pairs of meetings that overlap.
+	Conflicts []MeetingConflict `json:"conflicts"`
+
+	// Total credits of the schedule's courses, counting each course once.
+	// Courses with a range of credit values count for their minimum in
+	// TotalMinCredits and their maximum in TotalMaxCredits.
+	TotalMinCredits int `json:"total_min_credits"`
+	TotalMaxCredits int `json:"total_max_credits"`
+
+	// Sections in the request that don't exist.
+	Missing []SectionRef `json:"missing"`
+
+	// Sections in the schedule without any open seats.
+	Full []SectionRef `json:"full"`
+}
+
+// Check that a request is well-formed, normalizing its course and section
+// codes to upper case as elsewhere in the API. Codes are limited to letters
+// and digits, matching the codes used by Testudo.
+func (r *ScheduleCheckRequest) validate() error {
+	if len(r.Sections) == 0 {
+		return fmt.Errorf("must include at least one section")
+	}
+	if len(r.Sections) > maxScheduleSections {
+		return fmt.Errorf("cannot include more than %d sections", maxScheduleSections)
+	}
+	for i := range r.Sections {
+		ref := &r.Sections[i]
+		ref.CourseCode = strings.ToUpper(strings.TrimSpace(ref.CourseCode))
+		ref.SecCode = strings.ToUpper(strings.TrimSpace(ref.SecCode))
+		if !isAlphanumeric(ref.CourseCode) {
+			return fmt.Errorf("sections[%d]: invalid course_code %q", i, ref.CourseCode)
+		}
+		if !isAlphanumeric(ref.SecCode) {
+			return fmt.Errorf("sections[%d]: invalid sec_code %q", i, ref.SecCode)
+		}
+	}
+	return nil
+}
+
+func isAlphanumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve the sections in a schedule and check them for conflicts, missing
+// or full sections, and total credits.
+func checkSchedule(source DataSource, req ScheduleCheckRequest) (ScheduleCheckResult, error) {
+	result := ScheduleCheckResult{
+		Sections:  []Section{},
+		Conflicts: []MeetingConflict{},
+		Missing:   []SectionRef{},
+		Full:      []SectionRef{},
+	}
+
+	refs := []SectionRef{}
+	courseCodes := []string{}
+	for _, ref := range req.Sections {
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+		if !slices.Contains(courseCodes, ref.CourseCode) {
+			courseCodes = append(courseCodes, ref.CourseCode)
+		}
+	}
+
+	sections, err := fetchSectionsByRef(source, refs, courseCodes, req.Term)
+	if err != nil {
+		return result, err
+	}
+	for _, ref := range refs {
+		i := slices.IndexFunc(sections, func(s Section) bool { return refOf(s) == ref })
+		if i < 0 {
+			result.Missing = append(result.Missing, ref)
+			continue
+		}
+		result.Sections = append(result.Sections, sections[i])
+		if sections[i].OpenSeats <= 0 {
+			result.Full = append(result.Full, ref)
+		}
+	}
+	for i, a := range result.Sections {
+		for _, b := range result.Sections[i+1:] {
+			result.Conflicts = append(result.Conflicts, sectionConflicts(a, b)...)
+		}
+	}
+
+	courses, err := source.Courses(CoursesArgs{
+		CourseCodes: strings.Join(courseCodes, ","),
+		Term:        req.Term,
+		Limit:       len(courseCodes),
+	})
+	if err != nil {
+		return result, err
+	}
+	for _, c := range courses {
+		if !slices.ContainsFunc(result.Sections, func(s Section) bool { return s.CourseCode == c.CourseCode }) {
+			continue
+		}
+		result.TotalMinCredits += c.MinCredits
+		if c.MaxCredits != nil {
+			result.TotalMaxCredits += *c.MaxCredits
+		} else {
+			result.TotalMaxCredits += c.MinCredits
+		}
+	}
+	return result, nil
+}
+
+// Get the sections referenced by `refs`, which are all sections of one of
+// `courseCodes`. Sections that don't exist are omitted.
+func fetchSectionsByRef(source DataSource, refs []SectionRef, courseCodes []string, term string) ([]Section, error) {
+	args := SectionsArgs{
+		CourseCodes: strings.Join(courseCodes, ","),
+		Term:        term,
+	}
+	return fetchFiltered(0, len(refs), func(offset, limit int) ([]Section, error) {
+		page := args
+		page.Offset, page.Limit = offset, limit
+		return source.Sections(page)
+	}, func(s *Section) bool {
+		return slices.Contains(refs, refOf(*s))
+	})
+}
+
+// Get all pairs of overlapping meetings between two sections.
+func sectionConflicts(a, b Section) []MeetingConflict {
+	conflicts := []MeetingConflict{}
+	for _, ma := range a.ParsedMeetings {
+		for _, mb := range b.ParsedMeetings {
+			days := overlappingDays(ma, mb)
+			if len(days) == 0 {
+				continue
+			}
+			conflicts = append(conflicts, MeetingConflict{
+				Sections: [2]SectionRef{refOf(a), refOf(b)},
+				Meetings: [2]string{ma.Raw, mb.Raw},
+				Days:     days,
+			})
+		}
+	}
+	return conflicts
+}
+
+// Get the days on which two meetings overlap in time. Meetings without a set
+// time never overlap with anything.
+func overlappingDays(a, b Meeting) []string {
+	if a.StartMinutes == nil || b.StartMinutes == nil {
+		return nil
+	}
+	if *a.StartMinutes >= *b.EndMinutes || *b.StartMinutes >= *a.EndMinutes {
+		return nil
+	}
+	shared := dayMaskOf(a.Days) & dayMaskOf(b.Days)
+	days := []string{}
+	for i, day := range meetingDays {
+		if shared&(1<<i) != 0 {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+/* =============================== GENERATOR =============================== */
+
+// Bounds on schedule generation, so requests for many courses with many
+// sections can't cause an exponential amount of work.
+const (
+	maxGeneratorCourses   = 8
+	maxGeneratedSchedules = 1000
+	maxGeneratorSteps     = 200000
+)
+
+// Arguments for generating schedules.
+type GenerateSchedulesArgs struct {
+	// A comma-separated list of course codes; each schedule has exactly one
+	// section of each course.
+	CourseCodes string `form:"courseCodes" binding:"required"`
+
+	// Only use sections with open seats if true
+	OnlyOpen bool `form:"onlyOpen"`
+
+	// Filters on the days and times sections meet
+	MeetingFilter
+
+	// How to order schedules; instructor_rating orders by the average rating
+	// of each schedule's instructors, highest first.
+	// Default value: the order schedules are generated in
+	SortBy string `form:"sortBy" binding:"omitempty,oneof=instructor_rating"`
+
+	// The term (semester) to get data for, as YYYYMM; for example, 202601.
+	// Default value: the current default term
+	Term string `form:"term" binding:"omitempty,len=6,numeric"`
+
+	// Number of schedules to return per page.
+	// Default value: 20; Maximum value: 100
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+
+	// The offset of schedules to view.
+	// Default value: 0
+	Offset int `form:"offset" binding:"omitempty,min=0,max=999"`
+}
+
+func (g *GenerateSchedulesArgs) setDefaults() {
+	if g.Limit == 0 {
+		g.Limit = 20
+	}
+}
+
+// A generated schedule, with one section per requested course.
+type GeneratedSchedule struct {
+	Sections []Section `json:"sections"`
+
+	// Average rating of the schedule's instructors, or null if none of them
+	// have ratings. Only set when sorting by instructor rating.
+	AverageRating *float64 `json:"average_rating,omitempty"`
+}
+
+// A page of generated schedules.
+type GeneratedSchedules struct {
+	Schedules []GeneratedSchedule `json:"schedules"`
+
+	// Total number of schedules found.
+	Total int `json:"total"`
+
+	// True if generation stopped early because it reached its bounds, in
+	// which case more valid schedules may exist.
+	Truncated bool `json:"truncated"`
+
+	// Requested courses that don't exist or have no sections matching the
+	// filters; no schedules can be generated if any are listed.
+	UnavailableCourses []string `json:"unavailable_courses"`
+}
+
+// Validate the list of course codes to generate schedules for.
+func (g GenerateSchedulesArgs) courseCodes() ([]string, error) {
+	codes := []string{}
+	for _, code := range splitList(g.CourseCodes) {
+		if !isAlphanumeric(code) {
+			return nil, fmt.Errorf("invalid course code %q", code)
+		}
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) > maxGeneratorCourses {
+		return nil, fmt.Errorf("cannot generate schedules for more than %d courses", maxGeneratorCourses)
+	}
+	return codes, nil
+}
+
+// Generate schedules with one section of each course in `codes` such that no
+// two sections' meetings overlap.
+func generateSchedules(source DataSource, args GenerateSchedulesArgs,
+	codes []string, constraints meetingConstraints) (GeneratedSchedules, error) {
+	result := GeneratedSchedules{
+		Schedules:          []GeneratedSchedule{},
+		UnavailableCourses: []string{},
+	}
+
+	courses, err := source.CoursesWithSections(CoursesWithSectionsArgs{
+		CourseCodes: strings.Join(codes, ","),
+		OnlyOpen:    args.OnlyOpen,
+		Term:        args.Term,
+		Limit:       len(codes),
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Candidate sections for each course, in the order courses were requested
+	candidates := make([][]Section, len(codes))
+	for i, code := range codes {
+		j := slices.IndexFunc(courses, func(c CourseWithSections) bool { return c.CourseCode == code })
+		if j >= 0 {
+			for _, s := range courses[j].Sections {
+				if constraints.allows(s) {
+					candidates[i] = append(candidates[i], s)
+				}
+			}
+		}
+		if len(candidates[i]) == 0 {
+			result.UnavailableCourses = append(result.UnavailableCourses, code)
+		}
+	}
+	if len(result.UnavailableCourses) > 0 {
+		return result, nil
+	}
+
+	// Courses with the fewest candidates are placed first to prune early, but
+	// schedules list sections in the order courses were requested.
+	placement := make([]int, len(codes))
+	for i := range placement {
+		placement[i] = i
+	}
+	sort.SliceStable(placement, func(a, b int) bool {
+		return len(candidates[placement[a]]) < len(candidates[placement[b]])
+	})
+
+	chosen := make([]Section, len(codes))
+	steps := 0
+	var search func(depth int)
+	search = func(depth int) {
+		if result.Truncated {
+			return
+		}
+		if depth == len(placement) {
+			if len(result.Schedules) >= maxGeneratedSchedules {
+				result.Truncated = true
+				return
+			}
+			result.Schedules = append(result.Schedules, GeneratedSchedule{Sections: slices.Clone(chosen)})
+			return
+		}
+		course := placement[depth]
+		for _, candidate := range candidates[course] {
+			steps++
+			if steps > maxGeneratorSteps {
+				result.Truncated = true
+				return
+			}
+			fits := true
+			for _, placed := range placement[:depth] {
+				if sectionsConflict(candidate, chosen[placed]) {
+					fits = false
+					break
+				}
+			}
+			if fits {
+				chosen[course] = candidate
+				search(depth + 1)
+			}
+		}
+	}
+	search(0)
+	result.Total = len(result.Schedules)
+
+	if args.SortBy == "instructor_rating" {
+		if err := sortSchedulesByRating(source, result.Schedules); err != nil {
+			return result, err
+		}
+	}
+	start := min(args.Offset, len(result.Schedules))
+	end := min(start+args.Limit, len(result.Schedules))
+	result.Schedules = result.Schedules[start:end]
+	return result, nil
+}
+
+// Check whether two sections have any overlapping meetings.
+func sectionsConflict(a, b Section) bool {
+	for _, ma := range a.ParsedMeetings {
+		for _, mb := range b.ParsedMeetings {
+			if len(overlappingDays(ma, mb)) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Set the average instructor rating of each schedule and sort schedules by
+// it, highest first. Schedules without any rated instructors are last.
+func sortSchedulesByRating(source DataSource, schedules []GeneratedSchedule) error {
+	names := []string{}
+	for _, schedule := range schedules {
+		for _, s := range schedule.Sections {
+			for _, name := range s.Instructors {
+				if !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	ratings := map[string]float64{}
+	for batch := range slices.Chunk(names, instructorNamesPerQuery) {
+		instructors, err := source.Instructors(InstructorArgs{
+			InstructorNames: strings.Join(batch, ","),
+			Limit:           filteredPageSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, instructor := range instructors {
+			if instructor.AverageRating != nil {
+				ratings[instructor.Name] = *instructor.AverageRating
+			}
+		}
+	}
+
+	for i := range schedules {
+		sum, count := 0.0, 0
+		for _, s := range schedules[i].Sections {
+			for _, name := range s.Instructors {
+				if rating, ok := ratings[name]; ok {
+					sum += rating
+					count++
+				}
+			}
+		}
+		if count > 0 {
+			average := sum / float64(count)
+			schedules[i].AverageRating = &average
+		}
+	}
+	sort.SliceStable(schedules, func(i, j int) bool {
+		a, b := schedules[i].AverageRating, schedules[j].AverageRating
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a > *b
+	})
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Create a section of `code` with the given meeting strings.
+func sectionWithMeetings(code, secCode string, meetings ...string) Section {
+	return Section{CourseCode: code, SecCode: secCode, Meetings: meetings, ParsedMeetings: parseMeetings(meetings), OpenSeats: 1}
+}
+
+func TestScheduleCheckRequestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []SectionRef
+		want     []SectionRef
+		err      string
+	}{
+		{
+			name:     "normalized",
+			sections: []SectionRef{{CourseCode: " cmsc131 ", SecCode: "0101"}, {CourseCode: "MATH141", SecCode: "esg1"}},
+			want:     []SectionRef{{CourseCode: "CMSC131", SecCode: "0101"}, {CourseCode: "MATH141", SecCode: "ESG1"}},
+		},
+		{name: "empty", sections: []SectionRef{}, err: "must include at least one section"},
+		{
+			name:     "too many",
+			sections: make([]SectionRef, maxScheduleSections+1),
+			err:      fmt.Sprintf("cannot include more than %d sections", maxScheduleSections),
+		},
+		{
+			name:     "invalid course code",
+			sections: []SectionRef{{CourseCode: "CMSC131", SecCode: "0101"}, {CourseCode: "CMSC%", SecCode: "0101"}},
+			err:      `sections[1]: invalid course_code "CMSC%"`,
+		},
+		{
+			name:     "missing section code",
+			sections: []SectionRef{{CourseCode: "CMSC131"}},
+			err:      `sections[0]: invalid sec_code ""`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := ScheduleCheckRequest{Sections: test.sections}
+			err := req.validate()
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("error = %v, want %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, "sections", req.Sections, test.want)
+		})
+	}
+}
+
+func TestSectionConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want string
+	}{
+		{
+			name: "same time",
+			a:    []string{"MWF-9:00am-9:50am-IRB-0324"},
+			b:    []string{"MW-9:30am-10:45am-KEY-0106"},
+			want: `[{"sections":[{"course_code":"A","sec_code":"0101"},{"course_code":"B","sec_code":"0101"}],` +
+				`"meetings":["MWF-9:00am-9:50am-IRB-0324","MW-9:30am-10:45am-KEY-0106"],"days":["M","W"]}]`,
+		},
+		{
+			name: "online sync",
+			a:    []string{"TuTh-9:30am-10:45am-OnlineSync"},
+			b:    []string{"MWF-10:00am-10:50am-KEY-0106", "Tu-10:00am-10:15am-KEY-0106"},
+			want: `[{"sections":[{"course_code":"A","sec_code":"0101"},{"course_code":"B","sec_code":"0101"}],` +
+				`"meetings":["TuTh-9:30am-10:45am-OnlineSync","Tu-10:00am-10:15am-KEY-0106"],"days":["Tu"]}]`,
+		},
+		{
+			name: "back to back",
+			a:    []string{"MWF-9:00am-9:50am-IRB-0324"},
+			b:    []string{"MWF-9:50am-10:40am-IRB-0324"},
+			want: `[]`,
+		},
+		{
+			name: "different days",
+			a:    []string{"MWF-9:00am-9:50am-IRB-0324"},
+			b:    []string{"TuTh-9:00am-9:50am-IRB-0324"},
+			want: `[]`,
+		},
+		{
+			name: "without set times",
+			a:    []string{"OnlineAsync", "Unspecified", "TBA"},
+			b:    []string{"MWF-9:00am-9:50am-IRB-0324"},
+			want: `[]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := sectionWithMeetings("A", "0101", test.a...), sectionWithMeetings("B", "0101", test.b...)
+			assertJSON(t, "conflicts", sectionConflicts(a, b), json.RawMessage(test.want))
+			if got, want := sectionsConflict(a, b), test.want != `[]`; got != want {
+				t.Errorf("sectionsConflict = %t, want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckSchedule(t *testing.T) {
+	fixtures, err := NewFixtureSource("fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := checkSchedule(fixtures, ScheduleCheckRequest{
+		Term: "202508",
+		Sections: []SectionRef{
+			{CourseCode: "CMSC131", SecCode: "0101"},
+			{CourseCode: "MATH141", SecCode: "0101"},
+			{CourseCode: "CMSC131", SecCode: "0201"},
+			{CourseCode: "CMSC999", SecCode: "0101"},
+			{CourseCode: "CMSC131", SecCode: "0101"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := []string{}
+	for _, s := range result.Sections {
+		sections = append(sections, s.CourseCode+"-"+s.SecCode)
+	}
+	if got, want := strings.Join(sections, ","), "CMSC131-0101,MATH141-0101,CMSC131-0201"; got != want {
+		t.Errorf("sections = %s, want %s", got, want)
+	}
+	assertJSON(t, "conflicts", result.Conflicts, json.RawMessage(
+		`[{"sections":[{"course_code":"CMSC131","sec_code":"0101"},{"course_code":"MATH141","sec_code":"0101"}],`+
+			`"meetings":["MWF-9:00am-9:50am-IRB-0324","MWF-9:00am-9:50am-KEY-0106"],"days":["M","W","F"]}]`))
+	assertJSON(t, "missing", result.Missing, []SectionRef{{CourseCode: "CMSC999", SecCode: "0101"}})
+	assertJSON(t, "full", result.Full, []SectionRef{{CourseCode: "CMSC131", SecCode: "0201"}})
+	if result.TotalMinCredits != 8 || result.TotalMaxCredits != 8 {
+		t.Errorf("credits = %d-%d, want 8-8", result.TotalMinCredits, result.TotalMaxCredits)
+	}
+}
+
+func TestGenerateSchedules(t *testing.T) {
+	// Create a course with `n` sections, all meeting at `meeting`.
+	course := func(code string, n int, meeting string) ([]Course, []Section) {
+		sections := make([]Section, n)
+		for i := range sections {
+			sections[i] = sectionWithMeetings(code, fmt.Sprintf("%04d", i+1), meeting)
+		}
+		return []Course{{CourseCode: code}}, sections
+	}
+	tests := []struct {
+		name        string
+		courses     map[string]int
+		meetings    map[string]string
+		limit       int
+		total       int
+		truncated   bool
+		unavailable []string
+		first       string
+	}{
+		{
+			name:     "avoids conflicts",
+			courses:  map[string]int{"AAAA100": 3, "BBBB100": 2},
+			meetings: map[string]string{"AAAA100": "MWF-9:00am-9:50am-A-1", "BBBB100": "MW-9:30am-10:20am-B-1"},
+			limit:    20,
+			total:    0,
+		},
+		{
+			name:     "every combination",
+			courses:  map[string]int{"AAAA100": 3, "BBBB100": 2},
+			meetings: map[string]string{"AAAA100": "MWF-9:00am-9:50am-A-1", "BBBB100": "TuTh-9:30am-10:45am-B-1"},
+			limit:    2,
+			total:    6,
+			first:    "AAAA100-0001,BBBB100-0001",
+		},
+		{
+			name:        "unavailable course",
+			courses:     map[string]int{"AAAA100": 3, "BBBB100": 0},
+			meetings:    map[string]string{"AAAA100": "OnlineAsync"},
+			limit:       20,
+			unavailable: []string{"BBBB100"},
+		},
+		{
+			name:      "schedule bound",
+			courses:   map[string]int{"AAAA100": 40, "BBBB100": 40},
+			meetings:  map[string]string{"AAAA100": "OnlineAsync", "BBBB100": "OnlineAsync"},
+			limit:     1,
+			total:     maxGeneratedSchedules,
+			truncated: true,
+			first:     "AAAA100-0001,BBBB100-0001",
+		},
+		{
+			name:      "step bound",
+			courses:   map[string]int{"AAAA100": 500, "BBBB100": 500},
+			meetings:  map[string]string{"AAAA100": "MWF-9:00am-9:50am-A-1", "BBBB100": "MWF-9:00am-9:50am-B-1"},
+			limit:     20,
+			total:     0,
+			truncated: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixtures := &FixtureSource{}
+			for _, code := range []string{"AAAA100", "BBBB100"} {
+				courses, sections := course(code, test.courses[code], test.meetings[code])
+				fixtures.courses = append(fixtures.courses, courses...)
+				fixtures.sections = append(fixtures.sections, sections...)
+			}
+			args := GenerateSchedulesArgs{CourseCodes: "AAAA100,BBBB100", Limit: test.limit}
+			codes, err := args.courseCodes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := generateSchedules(fixtures, args, codes, meetingConstraints{allowedDays: allDays, endBefore: 24 * 60})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Total != test.total || result.Truncated != test.truncated {
+				t.Errorf("total = %d, truncated = %t; want %d, %t", result.Total, result.Truncated, test.total, test.truncated)
+			}
+			if test.unavailable == nil {
+				test.unavailable = []string{}
+			}
+			assertJSON(t, "unavailable", result.UnavailableCourses, test.unavailable)
+			if len(result.Schedules) > test.limit {
+				t.Errorf("got %d schedules, want at most %d", len(result.Schedules), test.limit)
+			}
+			if test.first != "" {
+				sections := []string{}
+				for _, s := range result.Schedules[0].Sections {
+					sections = append(sections, s.CourseCode+"-"+s.SecCode)
+				}
+				if got := strings.Join(sections, ","); got != test.first {
+					t.Errorf("first schedule = %s, want %s", got, test.first)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateSchedulesCourseCodes(t *testing.T) {
+	tests := []struct {
+		courseCodes string
+		want        []string
+		err         string
+	}{
+		{courseCodes: "CMSC131,MATH141,CMSC131", want: []string{"CMSC131", "MATH141"}},
+		{courseCodes: "CMSC131,MATH-141", err: `invalid course code "MATH-141"`},
+		{
+			courseCodes: "A1,A2,A3,A4,A5,A6,A7,A8,A9",
+			err:         fmt.Sprintf("cannot generate schedules for more than %d courses", maxGeneratorCourses),
+		},
+	}
+	for _, test := range tests {
+		codes, err := GenerateSchedulesArgs{CourseCodes: test.courseCodes}.courseCodes()
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("courseCodes(%s) error = %v, want %s", test.courseCodes, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("courseCodes(%s): %s", test.courseCodes, err)
+		}
+		assertJSON(t, "codes", codes, test.want)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Weights of a term appearing in each field of a course, relative to the
+// course description.
+const (
+	searchCodeWeight        = 8.0
+	searchNameWeight        = 3.0
+	searchDescriptionWeight = 1.0
+)
+
+// Multipliers applied to the score of terms that only match a query token
+// approximately.
+const (
+	searchPrefixFactor = 0.6
+	searchTypoFactor   = 0.5
+)
+
+// Words too common to be useful in search.
+var searchStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "into": true,
+	"is": true, "its": true, "of": true, "on": true, "or": true, "the": true,
+	"this": true, "that": true, "to": true, "with": true,
+}
+
+// Arguments for searching courses.
+type SearchArgs struct {
+	// The search query; for example, machine learning
+	Query string `form:"q" binding:"required,max=200"`
+
+	// The term (semester) to search, as YYYYMM; for example, 202601.
+	// Default value: the current default term
+	Term string `form:"term" binding:"omitempty,len=6,numeric"`
+
+	// Number of results to return per page.
+	// Default value: 20; Maximum value: 100
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+
+	// The offset of results to view.
+	// Default value: 0
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+func (s *SearchArgs) setDefaults() {
+	if s.Limit == 0 {
+		s.Limit = 20
+	}
+}
+
+// A course matching a search, with its relevance score; higher is better.
+type SearchResult struct {
+	Course
+	Score float64 `json:"score"`
+}
+
+// An inverted index over the code, name, and description of every course in
+// a term. Terms are stemmed, so "learning" and "learned" match each other.
+type searchIndex struct {
+	courses  []Course
+	postings map[string][]searchPosting
+
+	// All indexed terms, for finding terms close to a misspelled query token
+	vocabulary []string
+}
+
+// A course that contains a term, and the weighted number of times it does.
+type searchPosting struct {
+	course int
+	weight float64
+}
+
+// Build a search index over `courses`.
+func newSearchIndex(courses []Course) *searchIndex {
+	index := &searchIndex{courses: courses, postings: map[string][]searchPosting{}}
+	for i, course := range courses {
+		weights := map[string]float64{}
+		for _, term := range courseCodeTerms(course.CourseCode) {
+			weights[term] += searchCodeWeight
+		}
+		for _, term := range searchTerms(course.Name) {
+			weights[term] += searchNameWeight
+		}
+		if course.Description != nil {
+			for _, term := range searchTerms(*course.Description) {
+				weights[term] += searchDescriptionWeight
+			}
+		}
+		for term, weight := range weights {
+			index.postings[term] = append(index.postings[term], searchPosting{course: i, weight: weight})
+		}
+	}
+	for term := range index.postings {
+		index.vocabulary = append(index.vocabulary, term)
+	}
+	sort.Strings(index.vocabulary)
+	return index
+}
+
+// Search the index, returning matching courses ordered by relevance. Each
+// query token contributes the score of its best match, whether exact, a prefix
+// (for the last token, as it may not be fully typed), or a typo; scores are
+// scaled by the fraction of tokens a course matches.
+func (index *searchIndex) search(query string) []SearchResult {
+	tokens := searchTerms(query)
+	scores := map[int]float64{}
+	matched := map[int]int{}
+	for i, token := range tokens {
+		best := map[int]float64{}
+		for term, factor := range index.candidates(token, i == len(tokens)-1) {
+			postings := index.postings[term]
+			idf := math.Log(1 + (float64(len(index.courses))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+			for _, p := range postings {
+				// Saturate term frequency, as in BM25
+				score := factor * idf * p.weight * 2.2 / (p.weight + 1.2)
+				best[p.course] = max(best[p.course], score)
+			}
+		}
+		for course, score := range best {
+			scores[course] += score
+			matched[course]++
+		}
+	}
+
+	// Searching for an exact course code puts that course first
+	code := strings.ToUpper(strings.Join(strings.Fields(query), ""))
+	results := []SearchResult{}
+	for course, score := range scores {
+		score *= float64(matched[course]) / float64(len(tokens))
+		if index.courses[course].CourseCode == code {
+			score += 100
+		}
+		results = append(results, SearchResult{
+			Course: index.courses[course],
+			Score:  math.Round(score*1000) / 1000,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].CourseCode < results[j].CourseCode
+	})
+	return results
+}
+
+// Get the indexed terms that match a query token, and the factor to multiply
+// each term's score by.
+func (index *searchIndex) candidates(token string, isLast bool) map[string]float64 {
+	candidates := map[string]float64{}
+	if _, ok := index.postings[token]; ok {
+		candidates[token] = 1
+	}
+	if isLast && len(token) >= 3 {
+		start := sort.SearchStrings(index.vocabulary, token)
+		for _, term := range index.vocabulary[start:] {
+			if !strings.HasPrefix(term, token) {
+				break
+			}
+			if term != token {
+				candidates[term] = searchPrefixFactor
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates
+	}
+
+	// Only fall back to typos if nothing matches, so close but different words
+	// don't crowd out exact matches
+	maxDistance := 0
+	switch {
+	case len(token) >= 8:
+		maxDistance = 2
+	case len(token) >= 4:
+		maxDistance = 1
+	}
+	if maxDistance == 0 {
+		return candidates
+	}
+	for _, term := range index.vocabulary {
+		if editDistance(token, term, maxDistance) <= maxDistance {
+			candidates[term] = searchTypoFactor
+		}
+	}
+	return candidates
+}
+
+// Split text into lowercase, stemmed terms, without stop words.
+func searchTerms(text string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if searchStopWords[word] {
+			continue
+		}
+		term := stem(word)
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Terms for a course code, so a course can be found by its full code, its
+// department, or its number; for example, cmsc131, cmsc, and 131.
+func courseCodeTerms(code string) []string {
+	code = strings.ToLower(code)
+	terms := []string{code}
+	if len(code) > 4 {
+		terms = append(terms, code[:4], code[4:])
+	}
+	return terms
+}
+
+// Reduce an English word to a stem by removing common suffixes. This is much
+// simpler than a full stemmer, but only needs to map forms of the same word
+// to the same term, not produce real words.
+func stem(word string) string {
+	if len(word) <= 3 || strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		word = strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"):
+		word = strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = strings.TrimSuffix(word, "s")
+	}
+	for _, suffix := range []string{"ational", "ation", "ing", "ed", "er", "ly"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			word = strings.TrimSuffix(word, suffix)
+			if n := len(word); word[n-1] == word[n-2] && !strings.ContainsRune("aeiouls", rune(word[n-1])) {
+				word = word[:n-1] // programming -> program
+			}
+			break
+		}
+	}
+	if len(word) > 4 && strings.HasSuffix(word, "e") {
+		word = strings.TrimSuffix(word, "e")
+	}
+	return word
+}
+
+// The number of single-character insertions, deletions, substitutions, or
+// adjacent transpositions needed to turn a into b. Returns limit+1 once the
+// distance is known to be greater than limit.
+func editDistance(a, b string, limit int) int {
+	if abs(len(a)-len(b)) > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+/* ================================= CACHE ================================= */
+
+// Get the search index for `term`, building it from all courses in the term if
+// it isn't cached.
+func (server Server) getSearchIndex(term string) (*searchIndex, error) {
+	return server.searchIndexes.get(term, func() (*searchIndex, error) {
+		courses, err := fetchAllCourses(server.source, term)
+		if err != nil {
+			return nil, err
+		}
+		return newSearchIndex(courses), nil
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{words: []string{"learn", "learns", "learned", "learning", "learner"}, want: "learn"},
+		{words: []string{"compute", "computer", "computers", "computation"}, want: "comput"},
+		{words: []string{"program", "programs", "programming", "programmed"}, want: "program"},
+		{words: []string{"study", "studies"}, want: "study"},
+		{words: []string{"class", "classes"}, want: "class"},
+		{words: []string{"call", "calling", "called"}, want: "call"},
+		{words: []string{"analysis"}, want: "analysis"},
+		{words: []string{"calculus"}, want: "calculus"},
+		{words: []string{"bus"}, want: "bus"},
+		{words: []string{"cmsc131"}, want: "cmsc131"},
+	}
+	for _, test := range tests {
+		for _, word := range test.words {
+			if got := stem(word); got != test.want {
+				t.Errorf("stem(%q) = %q, want %q", word, got, test.want)
+			}
+		}
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "The Learning of Machines, and machine learning!", want: []string{"learn", "machin"}},
+		{text: "Object-Oriented Programming I", want: []string{"object", "orient", "program", "i"}},
+		{text: "CMSC131: Calculus", want: []string{"cmsc131", "calculus"}},
+		{text: "the and of", want: []string{}},
+	}
+	for _, test := range tests {
+		assertJSON(t, test.text, searchTerms(test.text), test.want)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{a: "same", b: "same", limit: 1, want: 0},
+		{a: "algoritm", b: "algorithm", limit: 2, want: 1},
+		{a: "teh", b: "the", limit: 1, want: 1},
+		{a: "kitten", b: "sitting", limit: 3, want: 3},
+		{a: "abcd", b: "wxyz", limit: 1, want: 2},
+		{a: "abc", b: "abcdef", limit: 2, want: 3},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b, test.limit); got != test.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", test.a, test.b, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	description := func(s string) *string { return &s }
+	index := newSearchIndex([]Course{
+		{CourseCode: "CMSC131", Name: "Object-Oriented Programming I",
+			Description: description("Introduction to programming and computer science.")},
+		{CourseCode: "CMSC320", Name: "Introduction to Data Science",
+			Description: description("Data analysis and machine learning.")},
+		{CourseCode: "CMSC422", Name: "Introduction to Machine Learning",
+			Description: description("Machine learning algorithms.")},
+		{CourseCode: "MATH141", Name: "Calculus II"},
+	})
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "exact", query: "machine learning", want: "CMSC422,CMSC320"},
+		{name: "stemmed", query: "Learned", want: "CMSC422,CMSC320"},
+		{name: "prefix of the last token", query: "progr", want: "CMSC131"},
+		{name: "prefix of another token", query: "progr science", want: "CMSC320,CMSC131"},
+		{name: "typo", query: "calclus", want: "MATH141"},
+		{name: "typos in a long token", query: "algoritms", want: "CMSC422"},
+		{name: "too short for a typo", query: "dta", want: ""},
+		{name: "course code", query: "cmsc 131", want: "CMSC131,CMSC320,CMSC422"},
+		{name: "department", query: "MATH", want: "MATH141"},
+		{name: "no match", query: "xyzzy", want: ""},
+		{name: "only stop words", query: "the", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			codes := []string{}
+			for _, result := range index.search(test.query) {
+				codes = append(codes, result.CourseCode)
+			}
+			if got := strings.Join(codes, ","); got != test.want {
+				t.Errorf("search(%q) = %s, want %s", test.query, got, test.want)
+			}
+		})
+	}
+
+	exact, typo := index.search("calculus"), index.search("calclus")
+	if len(exact) != 1 || len(typo) != 1 || typo[0].Score >= exact[0].Score {
+		t.Errorf("search(calclus) = %+v, want a lower score than search(calculus) = %+v", typo, exact)
+	}
+}
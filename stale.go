@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How long a stale payload is served without refreshing it again after a
+// refresh fails.
+const staleRetryInterval = 30 * time.Second
+
+// Warnings (RFC 7234) sent with stale payloads.
+const (
+	warningStale            = `110 - "Response is Stale"`
+	warningRevalidateFailed = `111 - "Revalidation Failed"`
+)
+
+// Metric keys in `cacheMetrics`.
+const (
+	// Stale payloads served while refreshing them in the background
+	metricStale = "stale"
+
+	// Stale payloads served because refreshing them failed
+	metricStaleOnError = "staleOnError"
+)
+
+// Key in the gin context of the payload to serve if fetching fails; see
+// `serveFromCache`.
+const staleFallbackKey = "staleFallback"
+
+// Key in the context of a request made by `revalidate`, holding the flight it
+// leads.
+type revalidationKey struct{}
+
+// A stale payload to serve if fetching a fresh one fails.
+type staleFallback struct {
+	cache   *LRUCache
+	key     string
+	payload *cachedPayload
+	age     time.Duration
+}
+
+// Copy `payload` with the headers of a stale response: Age, the seconds since
+// it was fetched, and a Warning.
+func withStaleHeaders(payload *cachedPayload, age time.Duration, warning string) *cachedPayload {
+	stale := withAge(payload, age)
+	stale.header.Set("Warning", warning)
+	return stale
+}
+
+// Send the stale payload for the request in `ctx` instead of `payload` if
+// `payload` is a server error and there is one; otherwise, returns `payload`.
+func useStaleOnError(ctx *gin.Context, payload *cachedPayload) *cachedPayload {
+	if payload.status < http.StatusInternalServerError {
+		return payload
+	}
+	value, ok := ctx.Get(staleFallbackKey)
+	if !ok {
+		return payload
+	}
+	fallback := value.(*staleFallback)
+	log.Printf("Serving stale payload for key %s after status %d", fallback.key, payload.status)
+	cacheMetrics.Add(metricStaleOnError, 1)
+	fallback.cache.Postpone(fallback.key, staleRetryInterval)
+	return withStaleHeaders(fallback.payload, fallback.age, warningRevalidateFailed)
+}
+
+// Refresh the stale payload for `key` in the background, unless it is already
+// being fetched. The request in `ctx` is replayed with its response discarded,
+// so the handler fetches and caches a fresh payload as on a cache miss.
+func (server Server) revalidate(ctx *gin.Context, key string) {
+	f, leader := server.flights.join(key)
+	if !leader {
+		return
+	}
+	cacheMetrics.Add(metricFetches, 1)
+	req := ctx.Request.Clone(context.WithValue(context.Background(), revalidationKey{}, f))
+	go func() {
+		defer f.finish(nil)
+		server.router.ServeHTTP(discardResponse{header: http.Header{}}, req)
+	}()
+}
+
+// An http.ResponseWriter that discards the response, for requests made by
+// `revalidate`.
+type discardResponse struct {
+	header http.Header
+}
+
+func (w discardResponse) Header() http.Header {
+	return w.header
+}
+
+func (w discardResponse) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w discardResponse) WriteHeader(int) {}
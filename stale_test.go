@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A data source whose departments can be made to fail, counting how often
+// they're fetched.
+type flakySource struct {
+	DataSource
+	failing atomic.Bool
+	fetches atomic.Int32
+}
+
+func (s *flakySource) Departments() ([]Department, error) {
+	s.fetches.Add(1)
+	if s.failing.Load() {
+		return nil, errors.New("database is down")
+	}
+	return s.DataSource.Departments()
+}
+
+// Move every entry in `c` back in time by `d`, as if it had been cached `d`
+// earlier.
+func ageEntries(c *LRUCache, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, elem := range c.items {
+		entry := elem.Value.(*cacheEntry)
+		entry.storedAt = entry.storedAt.Add(-d)
+		entry.expiresAt = entry.expiresAt.Add(-d)
+		entry.revalidateUntil = entry.revalidateUntil.Add(-d)
+		entry.staleUntil = entry.staleUntil.Add(-d)
+		entry.retryAt = entry.retryAt.Add(-d)
+	}
+}
+
+func TestStaleResponses(t *testing.T) {
+	const key = "GET:/v0/deptList"
+	server := newTestServer(t)
+	source := &flakySource{DataSource: server.source}
+	server = newServer(source, server.cache)
+
+	tests := []struct {
+		name    string
+		age     time.Duration
+		failing bool
+		status  int
+		warning string
+		fetches int32
+	}{
+		{name: "miss", status: http.StatusOK, fetches: 1},
+		{name: "fresh", age: departmentsTTL - time.Second, status: http.StatusOK, fetches: 1},
+		{name: "stale", age: departmentsTTL, status: http.StatusOK, warning: warningStale, fetches: 2},
+		{name: "refreshed", status: http.StatusOK, fetches: 2},
+		{name: "expired", age: 2 * departmentsTTL, status: http.StatusOK, fetches: 3},
+		{name: "expired and failing", age: 2 * departmentsTTL, failing: true, status: http.StatusOK, warning: warningRevalidateFailed, fetches: 4},
+		{name: "postponed after failing", failing: true, status: http.StatusOK, warning: warningRevalidateFailed, fetches: 4},
+		{name: "retried after failing", age: staleRetryInterval, status: http.StatusOK, warning: warningRevalidateFailed, fetches: 5},
+		{name: "recovered", status: http.StatusOK, fetches: 5},
+		{name: "too old and failing", age: departmentsTTL + defaultMaxStale, failing: true, status: http.StatusInternalServerError, fetches: 6},
+	}
+	for _, test := range tests {
+		ageEntries(server.cache, test.age)
+		source.failing.Store(test.failing)
+		w := serve(server, http.MethodGet, "/v0/deptList", "")
+		// Wait for any refresh in the background to finish
+		for isFlying(server.flights, key) {
+			time.Sleep(time.Millisecond)
+		}
+		if w.Code != test.status {
+			t.Errorf("%s: status = %d, want %d", test.name, w.Code, test.status)
+		}
+		if got := w.Header().Get("Warning"); got != test.warning {
+			t.Errorf("%s: Warning = %q, want %q", test.name, got, test.warning)
+		}
+		if got := source.fetches.Load(); got != test.fetches {
+			t.Errorf("%s: fetched %d times, want %d", test.name, got, test.fetches)
+		}
+	}
+}
@@ -1,20 +1,30 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // A SupabaseClient connects with Supabase and retrieves course, section,
-// or instructor data.
+// or instructor data. It implements DataSource by translating args into
+// PostgREST queries.
 type SupabaseClient struct {
-	Url   string
-	Key   string
-	cache *LRUCache
+	Url string
+	Key string
 }
 
+// Maximum time to wait for a response from the database, after which the
+// request fails and a stale cached payload is served if there is one.
+const upstreamTimeout = 10 * time.Second
+
+var httpClient = &http.Client{Timeout: upstreamTimeout}
+
 // Request data from the `table` with the given query parameters `params`.
 //
 // Example use:
@@ -28,67 +38,108 @@ type SupabaseClient struct {
 //	params.Set("limit", "1")
 //	res, err := s.request(table, params.Encode()) // SELECT * FROM courses LIMIT 1
 func (s SupabaseClient) request(table string, params string) (*http.Response, error) {
+	return httpClient.Do(s.newRequest(table, params))
+}
+
+func (s SupabaseClient) newRequest(table string, params string) *http.Request {
 	fullUrl := s.Url + "/rest/v1/" + table + "?" + params
 	method := "GET"                                 // GET requests will always be used
 	req, _ := http.NewRequest(method, fullUrl, nil) // body always nil when getting data
 	req.Header.Set("apikey", s.Key)
 	req.Header.Set("Authorization", "Bearer "+s.Key)
 	req.Header.Set("Content-Type", "application/json")
-	return http.DefaultClient.Do(req)
+	return req
+}
+
+// Count the rows in `table` that match the filters in `params`, using
+// PostgREST's exact count. No rows are retrieved; PostgREST reports the count
+// in the Content-Range header, as in */1234.
+func (s SupabaseClient) count(table string, params url.Values) (int, error) {
+	params.Del("offset")
+	params.Del("order")
+	params.Set("limit", "0")
+	req := s.newRequest(table, params.Encode())
+	req.Header.Set("Prefer", "count=exact")
+	res, err := httpClient.Do(req)
+	if _, err := decodeResponse[[]json.RawMessage](res, err); err != nil {
+		return 0, err
+	}
+	_, total, _ := strings.Cut(res.Header.Get("Content-Range"), "/")
+	n, err := strconv.Atoi(total)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Content-Range %q in count of %s", res.Header.Get("Content-Range"), table)
+	}
+	return n, nil
 }
 
 // Get a list of courses, without section info, that match the given args.
 // Returns the columns provided as an argument.
 func (s SupabaseClient) getCourses(args CoursesArgs, columns []string) (*http.Response, error) {
+	return s.request("courses", coursesQuery(args, columns).Encode())
+}
+
+// Build the query for courses, without section info, that match the given args.
+func coursesQuery(args CoursesArgs, columns []string) url.Values {
 	// SELECT `columns` FROM courses
 	// WHERE course_code LIKE `args.Prefix`*
 	// / WHERE course_code IN `args.CourseCodes`
 	// / WHERE course_code LIKE ____`args.Number`*
 	// AND `args.GenEds` IN gen_eds
 	// AND credits `args.Credits`
+	// AND term = `args.Term`
 	// OFFSET `args.Offset` LIMIT `args.Limit`
 	// SORT BY `args.SortBy`
 	params := url.Values{}
 	columnsStr := strings.Join(columns, ",")
 	params.Set("select", columnsStr)
 	if args.CourseCodes != "" {
-		params.Set("course_code", fmt.Sprintf("in.(%s)", args.CourseCodes))
+		params.Set("course_code", "in."+postgrestList(splitList(args.CourseCodes)))
 	} else if args.Prefix != "" {
-		params.Set("course_code", fmt.Sprintf("like.%s*", args.Prefix))
+		params.Set("course_code", "like."+postgrestLikeLiteral(args.Prefix)+"*")
 	} else if args.Number != "" {
-		params.Set("course_code", fmt.Sprintf("like.____%s*", args.Number))
+		params.Set("course_code", "like.____"+postgrestLikeLiteral(args.Number)+"*")
 	}
 	if args.GenEds != "" {
-		params.Set("gen_eds", fmt.Sprintf("cs.{%s}", args.GenEds))
+		params.Set("gen_eds", "cs."+postgrestArray(splitList(args.GenEds)))
 	}
 	for _, cond := range args.Credits {
 		params.Add("min_credits", cond)
 	}
+	if args.Term != "" {
+		params.Set("term", fmt.Sprintf("eq.%s", args.Term))
+	}
 	params.Set("offset", fmt.Sprintf("%d", args.Offset))
 	params.Set("limit", fmt.Sprintf("%d", args.Limit))
 	if args.SortBy != "" {
 		params.Set("order", args.SortBy)
 	}
-	return s.request("courses", params.Encode())
+	return params
 }
 
 // Get a list of sections for one or many courses.
 func (s SupabaseClient) getSections(args SectionsArgs) (*http.Response, error) {
+	return s.request("sections", sectionsQuery(args).Encode())
+}
+
+// Build the query for sections that match the given args.
+func sectionsQuery(args SectionsArgs) url.Values {
 	// SELECT * FROM sections
 	// WHERE course_code IN `args.CourseCodes` / WHERE course_code LIKE `args.CoursePrefix`*
 	// AND credits `args.Credits`
 	// AND total_seats `args.TotalClassSize`
 	// AND open_seats > 0 (if `args.OnlyOpen` is true)
 	// AND `args.Instructor` = ANY(instructors)
+	// AND instructors && `args.AnyInstructor`
+	// AND term = `args.Term`
 	// OFFSET `args.Offset` LIMIT `args.Limit`
 	// SORT BY `args.SortBy`
 	params := url.Values{}
 	params.Set("select", "*")
 	if args.CourseCodes != "" {
-		params.Set("course_code", fmt.Sprintf("in.(%s)", args.CourseCodes))
+		params.Set("course_code", "in."+postgrestList(splitList(args.CourseCodes)))
 	}
 	if args.CoursePrefix != "" {
-		params.Set("course_code", fmt.Sprintf("like.%s*", args.CoursePrefix))
+		params.Set("course_code", "like."+postgrestLikeLiteral(args.CoursePrefix)+"*")
 	}
 	params.Set("offset", fmt.Sprintf("%d", args.Offset))
 	params.Set("limit", fmt.Sprintf("%d", args.Limit))
@@ -104,28 +155,42 @@ func (s SupabaseClient) getSections(args SectionsArgs) (*http.Response, error) {
 		params.Add("open_seats", "gt.0")
 	}
 	if args.Instructor != "" {
-		params.Set("instructors", fmt.Sprintf("cs.{%s}", args.Instructor))
+		params.Set("instructors", "cs."+postgrestArray([]string{args.Instructor}))
 	}
-	return s.request("sections", params.Encode())
+	if args.AnyInstructor != nil {
+		params.Add("instructors", "ov."+postgrestArray(args.AnyInstructor))
+	}
+	if args.Term != "" {
+		params.Set("term", fmt.Sprintf("eq.%s", args.Term))
+	}
+	return params
 }
 
 func (s SupabaseClient) getCoursesWithSections(args CoursesWithSectionsArgs) (*http.Response, error) {
+	return s.request("courses", coursesWithSectionsQuery(args).Encode())
+}
+
+// Build the query for courses with sections that match the given args.
+func coursesWithSectionsQuery(args CoursesWithSectionsArgs) url.Values {
 	// SELECT * FROM courses
 	// INNER JOIN sections ON courses.course_code = sections.course_code
 	// AND sections.total_seats `args.TotalClassSize`
 	// AND sections.open_seats > 0 (if `args.OnlyOpen` is true)
 	// AND sections.`args.Instructor` = ANY(instructors)`
+	// AND sections.instructors && `args.AnyInstructor`
+	// AND sections.term = `args.Term`
 	// WHERE course_code LIKE `args.Prefix`*
 	// / WHERE course_code IN `args.CourseCodes`
 	// / WHERE course_code LIKE ____`args.Number`*
 	// AND `args.GenEds` IN gen_eds
 	// AND credits `args.Credits`
+	// AND term = `args.Term`
 	// OFFSET `args.Offset` LIMIT `args.Limit`
 	// SORT BY `args.SortBy`
 
 	params := url.Values{}
 	selectStr := "*,sections"
-	if args.TotalClassSize != nil || args.OnlyOpen || args.Instructor != "" {
+	if args.TotalClassSize != nil || args.OnlyOpen || args.Instructor != "" || args.AnyInstructor != nil {
 		selectStr += "!inner(*)"
 	} else {
 		selectStr += "(*)"
@@ -139,55 +204,73 @@ func (s SupabaseClient) getCoursesWithSections(args CoursesWithSectionsArgs) (*h
 		params.Add("sections.open_seats", "gt.0")
 	}
 	if args.Instructor != "" {
-		params.Set("sections.instructors", fmt.Sprintf("cs.{%s}", args.Instructor))
+		params.Set("sections.instructors", "cs."+postgrestArray([]string{args.Instructor}))
+	}
+	if args.AnyInstructor != nil {
+		params.Add("sections.instructors", "ov."+postgrestArray(args.AnyInstructor))
+	}
+	if args.Term != "" {
+		params.Set("sections.term", fmt.Sprintf("eq.%s", args.Term))
 	}
 	params.Set("select", selectStr)
 	if args.CourseCodes != "" {
-		params.Set("course_code", fmt.Sprintf("in.(%s)", args.CourseCodes))
+		params.Set("course_code", "in."+postgrestList(splitList(args.CourseCodes)))
 	} else if args.Prefix != "" {
-		params.Set("course_code", fmt.Sprintf("like.%s*", args.Prefix))
+		params.Set("course_code", "like."+postgrestLikeLiteral(args.Prefix)+"*")
 	} else if args.Number != "" {
-		params.Set("course_code", fmt.Sprintf("like.____%s*", args.Number))
+		params.Set("course_code", "like.____"+postgrestLikeLiteral(args.Number)+"*")
 	}
 	if args.GenEds != "" {
-		params.Set("gen_eds", fmt.Sprintf("cs.{%s}", args.GenEds))
+		params.Set("gen_eds", "cs."+postgrestArray(splitList(args.GenEds)))
 	}
 	for _, cond := range args.Credits {
 		params.Add("min_credits", cond)
 	}
+	if args.Term != "" {
+		params.Set("term", fmt.Sprintf("eq.%s", args.Term))
+	}
 	params.Set("offset", fmt.Sprintf("%d", args.Offset))
 	params.Set("limit", fmt.Sprintf("%d", args.Limit))
 	if args.SortBy != "" {
 		params.Set("order", args.SortBy)
 	}
-	return s.request("courses", params.Encode())
+	return params
 }
 
 // Get a list of instructors (including inactive ones) and their ratings.
 func (s SupabaseClient) getInstructors(args InstructorArgs, table string) (*http.Response, error) {
+	return s.request(table, instructorsQuery(args).Encode())
+}
+
+// Build the query for instructors that match the given args.
+func instructorsQuery(args InstructorArgs) url.Values {
 	// SELECT * FROM instructors
 	// WHERE instructor_name IN `args.InstructorNames`
 	// AND instructor_slug IN `args.InstructorSlugs`
 	// AND ratings `args.Ratings`
+	// AND term = `args.Term` (active instructors only)
 	// OFFSET `args.Offset` LIMIT `args.Limit`
 	// SORT BY `args.SortBy`
 	params := url.Values{}
 	params.Set("select", "*")
 	if args.InstructorNames != "" {
-		params.Set("name", fmt.Sprintf("in.(%s)", args.InstructorNames))
+		params.Set("name", "in."+postgrestList(splitList(args.InstructorNames)))
 	}
 	if args.InstructorSlugs != "" {
-		params.Set("slug", fmt.Sprintf("in.(%s)", args.InstructorSlugs))
+		params.Set("slug", "in."+postgrestList(splitList(args.InstructorSlugs)))
 	}
 	for _, cond := range args.Ratings {
 		params.Add("average_rating", cond)
 	}
+	if args.Term != "" {
+		params.Set("term", fmt.Sprintf("eq.%s", args.Term))
+	}
 	params.Set("offset", fmt.Sprintf("%d", args.Offset))
 	params.Set("limit", fmt.Sprintf("%d", args.Limit))
 	if args.SortBy != "" {
 		params.Set("order", args.SortBy)
 	}
-	return s.request(table, params.Encode())
+	return params
 }
 
 // Get a list of all 4-letter department codes.
@@ -199,3 +282,108 @@ func (s SupabaseClient) getDepartments() (*http.Response, error) {
 	params.Set("order", "dept_code")
 	return s.request("departments", params.Encode())
 }
+
+// Get a list of all terms with data available.
+func (s SupabaseClient) getTerms() (*http.Response, error) {
+	// SELECT * FROM terms
+	// ORDER BY term
+	params := url.Values{}
+	params.Set("select", "*")
+	params.Set("order", "term")
+	return s.request("terms", params.Encode())
+}
+
+// Read the body of a PostgREST response and decode it into a value of type T.
+// Non-2xx responses are returned as an *UpstreamError.
+func decodeResponse[T any](res *http.Response, err error) (T, error) {
+	var result T
+	if err != nil {
+		return result, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return result, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return result, &UpstreamError{
+			Status: res.StatusCode,
+			Header: res.Header,
+			Body:   body,
+		}
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return result, fmt.Errorf("failed to decode %s response: %w", res.Request.URL.Path, err)
+	}
+	return result, nil
+}
+
+/* ============================== DATA SOURCE ============================== */
+
+func (s SupabaseClient) Courses(args CoursesArgs) ([]Course, error) {
+	return decodeResponse[[]Course](s.getCourses(args, []string{"*"}))
+}
+
+func (s SupabaseClient) MinifiedCourses(args CoursesArgs) ([]MinifiedCourse, error) {
+	return decodeResponse[[]MinifiedCourse](s.getCourses(args, []string{"course_code", "name"}))
+}
+
+func (s SupabaseClient) CoursesWithSections(args CoursesWithSectionsArgs) ([]CourseWithSections, error) {
+	return decodeResponse[[]CourseWithSections](s.getCoursesWithSections(args))
+}
+
+func (s SupabaseClient) Sections(args SectionsArgs) ([]Section, error) {
+	return decodeResponse[[]Section](s.getSections(args))
+}
+
+func (s SupabaseClient) SectionInstructors(args SectionsArgs) ([][]string, error) {
+	params := sectionsQuery(args)
+	params.Set("select", "instructors")
+	rows, err := decodeResponse[[]struct {
+		Instructors []string `json:"instructors"`
+	}](s.request("sections", params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	names := make([][]string, len(rows))
+	for i, row := range rows {
+		names[i] = row.Instructors
+	}
+	return names, nil
+}
+
+func (s SupabaseClient) Instructors(args InstructorArgs) ([]Instructor, error) {
+	return decodeResponse[[]Instructor](s.getInstructors(args, "instructors"))
+}
+
+func (s SupabaseClient) ActiveInstructors(args InstructorArgs) ([]Instructor, error) {
+	return decodeResponse[[]Instructor](s.getInstructors(args, "active_instructors"))
+}
+
+func (s SupabaseClient) CountCourses(args CoursesArgs) (int, error) {
+	return s.count("courses", coursesQuery(args, []string{"course_code"}))
+}
+
+func (s SupabaseClient) CountCoursesWithSections(args CoursesWithSectionsArgs) (int, error) {
+	return s.count("courses", coursesWithSectionsQuery(args))
+}
+
+func (s SupabaseClient) CountSections(args SectionsArgs) (int, error) {
+	return s.count("sections", sectionsQuery(args))
+}
+
+func (s SupabaseClient) CountInstructors(args InstructorArgs) (int, error) {
+	return s.count("instructors", instructorsQuery(args))
+}
+
+func (s SupabaseClient) CountActiveInstructors(args InstructorArgs) (int, error) {
+	return s.count("active_instructors", instructorsQuery(args))
+}
+
+func (s SupabaseClient) Departments() ([]Department, error) {
+	return decodeResponse[[]Department](s.getDepartments())
+}
+
+func (s SupabaseClient) Terms() ([]Term, error) {
+	return decodeResponse[[]Term](s.getTerms())
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// What a PostgREST filter is read as: an operator and its operand, which is
+// the elements of a list or array, a literal matched by a like pattern, or a
+// single value.
+type queryFilter struct {
+	op       string
+	elements []string
+
+	// For like patterns, the wildcards before the literal; every pattern
+	// ends with a * wildcard after the literal
+	prefix  string
+	literal string
+}
+
+// Params in a query that aren't filters.
+var queryOptionParams = []string{"select", "offset", "limit", "order"}
+
+func FuzzQueries(f *testing.F) {
+	for _, value := range trickyValues {
+		f.Add(value, "CMSC131")
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		list := a + "," + b
+		checkQuery(t, coursesQuery(CoursesArgs{CourseCodes: list, GenEds: a, Term: b, Limit: 10}, []string{"*"}), map[string][]queryFilter{
+			"course_code": {{op: "in", elements: splitList(list)}},
+			"gen_eds":     ifSet(a, queryFilter{op: "cs", elements: splitList(a)}),
+			"term":        ifSet(b, queryFilter{op: "eq", literal: b}),
+		})
+		checkQuery(t, coursesQuery(CoursesArgs{Prefix: a, Limit: 10}, []string{"*"}), map[string][]queryFilter{
+			"course_code": ifSet(a, queryFilter{op: "like", literal: a}),
+		})
+		checkQuery(t, coursesQuery(CoursesArgs{Number: b, Limit: 10}, []string{"*"}), map[string][]queryFilter{
+			"course_code": ifSet(b, queryFilter{op: "like", prefix: "____", literal: b}),
+		})
+
+		checkQuery(t, sectionsQuery(SectionsArgs{CourseCodes: list, Instructor: a, AnyInstructor: []string{a, b}, Term: b}), map[string][]queryFilter{
+			"course_code": {{op: "in", elements: splitList(list)}},
+			"instructors": append(ifSet(a, queryFilter{op: "cs", elements: []string{a}}), queryFilter{op: "ov", elements: []string{a, b}}),
+			"term":        ifSet(b, queryFilter{op: "eq", literal: b}),
+		})
+		checkQuery(t, sectionsQuery(SectionsArgs{CoursePrefix: a}), map[string][]queryFilter{
+			"course_code": ifSet(a, queryFilter{op: "like", literal: a}),
+		})
+
+		checkQuery(t, coursesWithSectionsQuery(CoursesWithSectionsArgs{
+			CourseCodes:   list,
+			GenEds:        a,
+			Term:          b,
+			Instructor:    b,
+			AnyInstructor: []string{a},
+		}), map[string][]queryFilter{
+			"course_code":          {{op: "in", elements: splitList(list)}},
+			"gen_eds":              ifSet(a, queryFilter{op: "cs", elements: splitList(a)}),
+			"term":                 ifSet(b, queryFilter{op: "eq", literal: b}),
+			"sections.instructors": append(ifSet(b, queryFilter{op: "cs", elements: []string{b}}), queryFilter{op: "ov", elements: []string{a}}),
+			"sections.term":        ifSet(b, queryFilter{op: "eq", literal: b}),
+		})
+
+		checkQuery(t, instructorsQuery(InstructorArgs{InstructorNames: list, InstructorSlugs: a, Term: b}), map[string][]queryFilter{
+			"name": {{op: "in", elements: splitList(list)}},
+			"slug": ifSet(a, queryFilter{op: "in", elements: splitList(a)}),
+			"term": ifSet(b, queryFilter{op: "eq", literal: b}),
+		})
+	})
+}
+
+// Get `filter` as the only filter expected for a param, or none if `value`
+// is empty.
+func ifSet(value string, filter queryFilter) []queryFilter {
+	if value == "" {
+		return nil
+	}
+	return []queryFilter{filter}
+}
+
+// Check that `query`, as sent to PostgREST, has no filters other than those
+// in `want`, and that each filter is read with the expected operator and
+// operand.
+func checkQuery(t *testing.T, query url.Values, want map[string][]queryFilter) {
+	t.Helper()
+	sent, err := url.ParseQuery(query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range sent {
+		if slices.Contains(queryOptionParams, name) {
+			continue
+		}
+		if len(want[name]) == 0 {
+			t.Fatalf("unexpected filter %s=%q", name, values)
+		}
+		got := []queryFilter{}
+		for _, value := range values {
+			filter, err := readFilter(value)
+			if err != nil {
+				t.Fatalf("%s=%s: %s", name, value, err)
+			}
+			got = append(got, filter)
+		}
+		if !reflect.DeepEqual(got, want[name]) {
+			t.Fatalf("%s=%q was read as %+v, want %+v", name, values, got, want[name])
+		}
+	}
+	for name, filters := range want {
+		if len(filters) > 0 && sent[name] == nil {
+			t.Fatalf("missing filter %s", name)
+		}
+	}
+}
+
+// Read a PostgREST filter such as in.("CMSC131") the way PostgREST does.
+func readFilter(value string) (queryFilter, error) {
+	op, operand, _ := strings.Cut(value, ".")
+	filter := queryFilter{op: op}
+	var err error
+	switch op {
+	case "in":
+		filter.elements, err = parseElements(operand, '(', ')')
+	case "cs", "ov":
+		filter.elements, err = parseElements(operand, '{', '}')
+	case "like":
+		filter.prefix, filter.literal, err = parseLikePattern(operand)
+	case "eq":
+		filter.literal = operand
+	default:
+		err = fmt.Errorf("unexpected operator %q", op)
+	}
+	return filter, err
+}
+
+// Parse a like pattern made of `_` wildcards, a literal, and a `*` wildcard,
+// returning the leading wildcards and the literal.
+func parseLikePattern(pattern string) (string, string, error) {
+	prefix := pattern[:len(pattern)-len(strings.TrimLeft(pattern, "_"))]
+	var literal strings.Builder
+	for i := len(prefix); i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+			if i == len(pattern) {
+				return "", "", errors.New("dangling backslash")
+			}
+		case '*':
+			if i != len(pattern)-1 {
+				return "", "", fmt.Errorf("unescaped wildcard * at %d", i)
+			}
+			return prefix, literal.String(), nil
+		case '%', '_':
+			return "", "", fmt.Errorf("unescaped wildcard %c at %d", pattern[i], i)
+		}
+		literal.WriteByte(pattern[i])
+	}
+	return "", "", errors.New("missing wildcard * at the end")
+}
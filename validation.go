@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Operators allowed in comparisons, such as credits=gt.3.
+var comparisonOperators = []string{"eq", "neq", "lt", "lte", "gt", "gte"}
+
+// Operands allowed in comparisons: plain decimal numbers, without exponents,
+// hex, or special values like NaN and Inf, which the database rejects or
+// compares unexpectedly. Integers are limited to 9 digits so they fit in the
+// database's integer columns.
+var (
+	integerOperand = regexp.MustCompile(`^-?[0-9]{1,9}$`)
+	decimalOperand = regexp.MustCompile(`^-?[0-9]{1,15}(\.[0-9]{1,15})?$`)
+)
+
+// Modifiers allowed after a column in a sort order, such as name.desc or
+// average_rating.desc.nullslast; each is optional, but they must be in order.
+var (
+	sortDirections = []string{"asc", "desc"}
+	sortNulls      = []string{"nullsfirst", "nullslast"}
+)
+
+// Custom validation tags for query args, by tag. Each function checks a field's
+// value, given the tag's parameter, and returns an error describing what's
+// wrong with it, which is sent to the caller by `sendInvalidArgsError`.
+var argValidators = map[string]func(value any, param string) error{
+	// A list of comparisons with numeric operands, such as gt.3; the operands
+	// must be integers if the parameter is int.
+	"comparisons": checkComparisons,
+
+	// A sort order using only the space-separated columns in the parameter,
+	// such as name.asc,min_credits.desc.
+	"sortcolumns": checkSortColumns,
+}
+
+// Register the custom validation tags in `argValidators` with gin, so they
+// can be used in `binding` struct tags.
+func registerArgValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return fmt.Errorf("unexpected validator engine %T", binding.Validator.Engine())
+	}
+	for tag, check := range argValidators {
+		err := v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return check(fl.Field().Interface(), fl.Param()) == nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkComparisons(value any, param string) error {
+	conds, ok := value.([]string)
+	if !ok {
+		return fmt.Errorf("expected a list of comparisons")
+	}
+	for _, cond := range conds {
+		op, operand, found := strings.Cut(cond, ".")
+		if !found || !slices.Contains(comparisonOperators, op) {
+			return fmt.Errorf("invalid comparison %q; must be an operator (%s) followed by a value, like gt.3",
+				cond, strings.Join(comparisonOperators, ", "))
+		}
+		if param == "int" {
+			if !integerOperand.MatchString(operand) {
+				return fmt.Errorf("invalid comparison %q; value must be an integer", cond)
+			}
+		} else if !decimalOperand.MatchString(operand) {
+			return fmt.Errorf("invalid comparison %q; value must be a number", cond)
+		}
+	}
+	return nil
+}
+
+func checkSortColumns(value any, param string) error {
+	order, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected a comma-separated list of columns")
+	}
+	columns := strings.Fields(param)
+	for _, part := range strings.Split(order, ",") {
+		fields := strings.Split(part, ".")
+		if !slices.Contains(columns, fields[0]) {
+			return fmt.Errorf("cannot sort by %q; sortable columns are %s", fields[0], strings.Join(columns, ", "))
+		}
+		modifiers := fields[1:]
+		if len(modifiers) > 0 && slices.Contains(sortDirections, modifiers[0]) {
+			modifiers = modifiers[1:]
+		}
+		if len(modifiers) > 0 && slices.Contains(sortNulls, modifiers[0]) {
+			modifiers = modifiers[1:]
+		}
+		if len(modifiers) > 0 {
+			return fmt.Errorf("invalid order %q; must be a column optionally followed by .asc or .desc, then "+
+				".nullsfirst or .nullslast", part)
+		}
+	}
+	return nil
+}
+
+// Describe why a field failed validation, for the invalidParams of a problem.
+func validationReason(e validator.FieldError) string {
+	unit := ""
+	if e.Kind() == reflect.String {
+		unit = " characters"
+	}
+	if check, ok := argValidators[e.Tag()]; ok {
+		return check(e.Value(), e.Param()).Error()
+	}
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "len":
+		return fmt.Sprintf("must be %s%s long", e.Param(), unit)
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s%s", e.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s%s", e.Param(), unit)
+	case "numeric":
+		return "must be numeric"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(e.Param()), ", "))
+	}
+	return fmt.Sprintf("failed the %s check", e.Tag())
+}